@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+//writeFileAtomic writes the content to a temp file in the same directory, fsyncs it and then renames it over `filePath`.
+//This way an observer polling the file will either see the previous or the new content, but never a truncated file.
+func writeFileAtomic(filePath string, content []byte, perm os.FileMode) (returnErr error) {
+	parentDir := filepath.Dir(filePath)
+
+	tmpFile, err := ioutil.TempFile(parentDir, "."+filepath.Base(filePath)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Cannot create temp file in dir '%s', error: %s", parentDir, err.Error())
+	}
+	tmpFilePath := tmpFile.Name()
+
+	defer func() {
+		if returnErr != nil {
+			tmpFile.Close()
+			os.Remove(tmpFilePath)
+		}
+	}()
+
+	if _, err = tmpFile.Write(content); err != nil {
+		return fmt.Errorf("Cannot write temp file '%s', error: %s", tmpFilePath, err.Error())
+	}
+	if err = tmpFile.Sync(); err != nil {
+		return fmt.Errorf("Cannot fsync temp file '%s', error: %s", tmpFilePath, err.Error())
+	}
+	if err = tmpFile.Close(); err != nil {
+		return fmt.Errorf("Cannot close temp file '%s', error: %s", tmpFilePath, err.Error())
+	}
+	if err = os.Chmod(tmpFilePath, perm); err != nil {
+		return fmt.Errorf("Cannot chmod temp file '%s', error: %s", tmpFilePath, err.Error())
+	}
+	if err = os.Rename(tmpFilePath, filePath); err != nil {
+		return fmt.Errorf("Cannot rename temp file '%s' to '%s', error: %s", tmpFilePath, filePath, err.Error())
+	}
+
+	syncDir(parentDir)
+	return nil
+}
+
+//syncDir is a best-effort fsync of the directory so the rename itself survives a crash. Not all OS'es (windows) support this.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	d.Sync()
+}
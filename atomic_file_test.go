@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	Convey("Testing writeFileAtomic", t, func() {
+		tmpDir, err := ioutil.TempDir("", "exec-logger-atomic")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tmpDir)
+
+		Convey("Replaces content and leaves no temp files behind", func() {
+			filePath := filepath.Join(tmpDir, "file.txt")
+			So(writeFileAtomic(filePath, []byte("first"), 0600), ShouldBeNil)
+			So(writeFileAtomic(filePath, []byte("second"), 0600), ShouldBeNil)
+
+			content, err := ioutil.ReadFile(filePath)
+			So(err, ShouldBeNil)
+			So(string(content), ShouldEqual, "second")
+
+			infos, err := ioutil.ReadDir(tmpDir)
+			So(err, ShouldBeNil)
+			So(len(infos), ShouldEqual, 1)
+		})
+
+		Convey("Concurrent readers never see partial exited json", func() {
+			handler := &execStatusHandler{exitedFilePath: filepath.Join(tmpDir, "exited.json")}
+			_, err := handler.WriteExitedJson(0, nil, time.Second, exec_logger_dtos.ExitOutcomeSuccess, nil, nil, nil)
+			So(err, ShouldBeNil)
+
+			longError := errors.New(strings.Repeat("error text ", 10000))
+
+			stop := make(chan struct{})
+			var readErrorsMutex sync.Mutex
+			readErrors := []string{}
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for {
+						select {
+						case <-stop:
+							return
+						default:
+						}
+
+						content, err := ioutil.ReadFile(handler.exitedFilePath)
+						if err != nil {
+							readErrorsMutex.Lock()
+							readErrors = append(readErrors, err.Error())
+							readErrorsMutex.Unlock()
+							continue
+						}
+						dto := &exec_logger_dtos.ExitStatusDto{}
+						if err = json.Unmarshal(content, dto); err != nil {
+							readErrorsMutex.Lock()
+							readErrors = append(readErrors, fmt.Sprintf("Invalid json of length %d, error: %s", len(content), err.Error()))
+							readErrorsMutex.Unlock()
+						}
+					}
+				}()
+			}
+
+			for i := 0; i < 200; i++ {
+				var writeErr error
+				if i%2 == 0 {
+					_, writeErr = handler.WriteExitedJson(1, longError, time.Second, exec_logger_dtos.ExitOutcomeFailed, nil, nil, nil)
+				} else {
+					_, writeErr = handler.WriteExitedJson(0, nil, time.Second, exec_logger_dtos.ExitOutcomeSuccess, nil, nil, nil)
+				}
+				So(writeErr, ShouldBeNil)
+			}
+			close(stop)
+			wg.Wait()
+
+			So(readErrors, ShouldBeEmpty)
+		})
+	})
+}
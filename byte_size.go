@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var byteSizeUnits = []struct {
+	Suffix     string
+	Multiplier int64
+}{
+	//Longest suffixes first so "KB" is not matched as "B"
+	{Suffix: "TB", Multiplier: 1024 * 1024 * 1024 * 1024},
+	{Suffix: "GB", Multiplier: 1024 * 1024 * 1024},
+	{Suffix: "MB", Multiplier: 1024 * 1024},
+	{Suffix: "KB", Multiplier: 1024},
+	{Suffix: "T", Multiplier: 1024 * 1024 * 1024 * 1024},
+	{Suffix: "G", Multiplier: 1024 * 1024 * 1024},
+	{Suffix: "M", Multiplier: 1024 * 1024},
+	{Suffix: "K", Multiplier: 1024},
+	{Suffix: "B", Multiplier: 1},
+}
+
+//parseByteSize parses sizes like "512MB", "1.5G" or "1024" (bytes). Units are powers of 1024
+func parseByteSize(s string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(s))
+	if trimmed == "" {
+		return 0, fmt.Errorf("Empty byte size")
+	}
+
+	numberPart := trimmed
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(trimmed, unit.Suffix) {
+			numberPart = strings.TrimSpace(strings.TrimSuffix(trimmed, unit.Suffix))
+			multiplier = unit.Multiplier
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(numberPart, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Cannot parse byte size '%s', error: %s", s, err.Error())
+	}
+	if number < 0 {
+		return 0, fmt.Errorf("Byte size '%s' cannot be negative", s)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+//formatByteSize formats the size with the largest unit that keeps it above 1, for example "1.5 GB"
+func formatByteSize(size int64) string {
+	for _, unit := range byteSizeUnits {
+		if len(unit.Suffix) != 2 {
+			continue
+		}
+		if size >= unit.Multiplier {
+			return fmt.Sprintf("%.1f %s", float64(size)/float64(unit.Multiplier), unit.Suffix)
+		}
+	}
+	return fmt.Sprintf("%d B", size)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestByteSize(t *testing.T) {
+	Convey("Testing parseByteSize", t, func() {
+		expectations := map[string]int64{
+			"1024":    1024,
+			"10B":     10,
+			"2k":      2048,
+			"512MB":   512 * 1024 * 1024,
+			"1.5G":    1536 * 1024 * 1024,
+			" 1 TB ":  1024 * 1024 * 1024 * 1024,
+			"0.5 kb":  512,
+			"100 MB ": 100 * 1024 * 1024,
+		}
+		for s, expected := range expectations {
+			actual, err := parseByteSize(s)
+			So(err, ShouldBeNil)
+			So(actual, ShouldEqual, expected)
+		}
+
+		for _, invalid := range []string{"", "MB", "abc", "-1G", "1.2.3K"} {
+			_, err := parseByteSize(invalid)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Testing formatByteSize", t, func() {
+		So(formatByteSize(10), ShouldEqual, "10 B")
+		So(formatByteSize(1536), ShouldEqual, "1.5 KB")
+		So(formatByteSize(3*1024*1024*1024), ShouldEqual, "3.0 GB")
+	})
+}
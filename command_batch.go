@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-zero-boilerplate/loggers"
+
+	"github.com/golang-devops/exec-logger/exec_logger_constants"
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/job_config"
+)
+
+//readBatchCommands reads one shell command per line, empty lines and lines starting with # are ignored
+func readBatchCommands(reader io.Reader) ([]string, error) {
+	commands := []string{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		commands = append(commands, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Cannot read batch commands, error: %s", err.Error())
+	}
+	return commands, nil
+}
+
+//batchRunner runs the commands of a batch, each with its own run directory
+type batchRunner struct {
+	logger      loggers.LoggerStdIO
+	options     execOptions
+	batchDir    string
+	concurrency int
+	failFast    bool
+
+	sync.Mutex
+	running map[int]*commandExecer
+	failed  bool
+}
+
+func (b *batchRunner) getRunDir(index int) string {
+	return filepath.Join(b.batchDir, fmt.Sprintf("%03d", index))
+}
+
+//start registers the running command, it returns false if the command must be skipped due to fail-fast
+func (b *batchRunner) start(index int, execer *commandExecer) bool {
+	b.Lock()
+	defer b.Unlock()
+	if b.failFast && b.failed {
+		return false
+	}
+	b.running[index] = execer
+	return true
+}
+
+func (b *batchRunner) finish(index int, failed bool) {
+	b.Lock()
+	defer b.Unlock()
+	delete(b.running, index)
+
+	if failed && b.failFast && !b.failed {
+		b.logger.Err("Command %d failed, aborting the %d running commands (fail-fast)", index, len(b.running))
+		for _, execer := range b.running {
+			execer.RequestAbort()
+		}
+	}
+	b.failed = b.failed || failed
+}
+
+func (b *batchRunner) runCommand(index int, commandLine string) *exec_logger_dtos.BatchCommandStatusDto {
+	status := &exec_logger_dtos.BatchCommandStatusDto{
+		Index:       index,
+		CommandLine: b.options.redactor.Redact(commandLine),
+		RunDir:      b.getRunDir(index),
+	}
+
+	options := b.options
+	options.runDir = status.RunDir
+	if options.outputTriggers != nil {
+		options.outputTriggers = options.outputTriggers.Clone()
+	}
+	execer := NewCommandExecer(b.logger, options, (&job_config.Config{Shell: commandLine}).RunArgs())
+
+	if !b.start(index, execer) {
+		status.Outcome = exec_logger_dtos.ExitOutcomeSkipped
+		return status
+	}
+
+	startTime := time.Now()
+	exitCode, err := execer.Run()
+	status.Duration = time.Now().Sub(startTime).String()
+	status.ExitCode = exitCode
+	status.Outcome = execer.Outcome()
+	if err != nil {
+		status.Error = b.options.redactor.Redact(err.Error())
+	}
+	b.logger.Out("Command %d finished with exit code %d (%s)", index, exitCode, status.Outcome)
+
+	b.finish(index, status.Outcome != exec_logger_dtos.ExitOutcomeSuccess)
+	return status
+}
+
+//run runs all the commands, at most `concurrency` at a time, and returns the summary
+func (b *batchRunner) run(commands []string) *exec_logger_dtos.BatchExitedDto {
+	summary := &exec_logger_dtos.BatchExitedDto{
+		StartTime:   time.Now().UTC(),
+		Concurrency: b.concurrency,
+		FailFast:    b.failFast,
+		Commands:    make([]*exec_logger_dtos.BatchCommandStatusDto, len(commands)),
+	}
+	b.running = make(map[int]*commandExecer)
+
+	semaphore := make(chan struct{}, b.concurrency)
+	var wg sync.WaitGroup
+	for i, commandLine := range commands {
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(index int, commandLine string) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			summary.Commands[index-1] = b.runCommand(index, commandLine)
+		}(i+1, commandLine)
+	}
+	wg.Wait()
+
+	summary.ExitTime = time.Now().UTC()
+	summary.Duration = summary.ExitTime.Sub(summary.StartTime).String()
+	summary.Count()
+	return summary
+}
+
+func writeBatchExitedFile(filePath string, summary *exec_logger_dtos.BatchExitedDto) error {
+	jsonBytes, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Cannot marshal batch summary to json, error: %s", err.Error())
+	}
+	return writeFileAtomic(filePath, jsonBytes, 0600)
+}
+
+//handleBatchCommand runs the commands of the batch file (or stdin if it is empty or -) and returns the summary
+func handleBatchCommand(logger loggers.LoggerStdIO, options execOptions, batchFilePath, batchDir string, concurrency int, failFast bool) (*exec_logger_dtos.BatchExitedDto, error) {
+	var reader io.Reader = os.Stdin
+	if batchFilePath != "" && batchFilePath != "-" {
+		file, err := os.Open(batchFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot open batch file '%s', error: %s", batchFilePath, err.Error())
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	commands, err := readBatchCommands(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(commands) == 0 {
+		return nil, fmt.Errorf("The batch has no commands")
+	}
+	if concurrency < 1 {
+		return nil, fmt.Errorf("The concurrency must be at least 1, got %d", concurrency)
+	}
+
+	if err := os.MkdirAll(batchDir, 0755); err != nil {
+		return nil, fmt.Errorf("Cannot create batch dir '%s', error: %s", batchDir, err.Error())
+	}
+
+	logger.Out("Running %d commands with a concurrency of %d in '%s'", len(commands), concurrency, batchDir)
+	runner := &batchRunner{
+		logger:      logger,
+		options:     options,
+		batchDir:    batchDir,
+		concurrency: concurrency,
+		failFast:    failFast,
+	}
+	summary := runner.run(commands)
+
+	batchExitedFilePath := filepath.Join(batchDir, exec_logger_constants.BATCH_EXITED_FILE_NAME)
+	if err := writeBatchExitedFile(batchExitedFilePath, summary); err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
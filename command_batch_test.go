@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+)
+
+func TestBatch(t *testing.T) {
+	Convey("Testing readBatchCommands", t, func() {
+		commands, err := readBatchCommands(strings.NewReader("# build\nmake build\n\n   \n  make test  \n#make deploy\n"))
+		So(err, ShouldBeNil)
+		So(commands, ShouldResemble, []string{"make build", "make test"})
+
+		commands, err = readBatchCommands(strings.NewReader(""))
+		So(err, ShouldBeNil)
+		So(commands, ShouldBeEmpty)
+	})
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	Convey("Testing batchRunner.run", t, func() {
+		batchDir, err := ioutil.TempDir("", "exec-logger-test-batch-")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(batchDir)
+
+		newRunner := func(concurrency int, failFast bool) *batchRunner {
+			return &batchRunner{
+				logger:      NewStdioLogger(),
+				batchDir:    batchDir,
+				concurrency: concurrency,
+				failFast:    failFast,
+			}
+		}
+		outcomes := func(summary *exec_logger_dtos.BatchExitedDto) (result []string) {
+			for _, command := range summary.Commands {
+				result = append(result, command.Outcome)
+			}
+			return result
+		}
+
+		Convey("At most `concurrency` commands run at the same time", func() {
+			startTime := time.Now()
+			summary := newRunner(2, false).run([]string{"sleep 1", "sleep 1", "sleep 1", "exit 3"})
+			duration := time.Now().Sub(startTime)
+
+			So(summary.Total, ShouldEqual, 4)
+			So(summary.Succeeded, ShouldEqual, 3)
+			So(summary.Failed, ShouldEqual, 1)
+			So(summary.Commands[3].ExitCode, ShouldEqual, 3)
+			So(summary.Commands[2].RunDir, ShouldEqual, batchDir+string(os.PathSeparator)+"003")
+			//The third command can only start after one of the first two finished
+			So(duration, ShouldBeGreaterThanOrEqualTo, 2*time.Second)
+		})
+
+		Convey("Without fail-fast all the commands run", func() {
+			summary := newRunner(1, false).run([]string{"exit 1", "echo second"})
+			So(outcomes(summary), ShouldResemble, []string{exec_logger_dtos.ExitOutcomeFailed, exec_logger_dtos.ExitOutcomeSuccess})
+		})
+
+		Convey("With fail-fast the next commands are skipped", func() {
+			summary := newRunner(1, true).run([]string{"echo first", "exit 1", "echo third", "echo fourth"})
+			So(outcomes(summary), ShouldResemble, []string{
+				exec_logger_dtos.ExitOutcomeSuccess,
+				exec_logger_dtos.ExitOutcomeFailed,
+				exec_logger_dtos.ExitOutcomeSkipped,
+				exec_logger_dtos.ExitOutcomeSkipped,
+			})
+			So(summary.Skipped, ShouldEqual, 2)
+		})
+
+		Convey("With fail-fast the running commands are aborted", func() {
+			startTime := time.Now()
+			summary := newRunner(2, true).run([]string{"sleep 20", "sleep 1; exit 1"})
+			So(outcomes(summary), ShouldResemble, []string{exec_logger_dtos.ExitOutcomeAborted, exec_logger_dtos.ExitOutcomeFailed})
+			So(time.Now().Sub(startTime), ShouldBeLessThan, 20*time.Second)
+		})
+	})
+}
@@ -6,45 +6,267 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-zero-boilerplate/loggers"
 
+	"github.com/golang-devops/exec-logger/diagnostics"
 	"github.com/golang-devops/exec-logger/exec_logger_constants"
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/log_patterns"
+	"github.com/golang-devops/exec-logger/output_actions"
 	"github.com/golang-devops/exec-logger/sleep_durations"
+	"github.com/golang-devops/exec-logger/webhooks"
 )
 
-func NewCommandExecer(logger loggers.LoggerStdIO, stdErrIsError bool, timeoutKillDuration time.Duration, recordResourceUsage bool, runArgs []string) *commandExecer {
+func NewCommandExecer(logger loggers.LoggerStdIO, options execOptions, runArgs []string) *commandExecer {
+	//The files are relative to the run directory, which is the current directory by default
+	inRunDir := func(fileName string) string {
+		return filepath.Join(options.runDir, fileName)
+	}
+
 	statusHandler := &execStatusHandler{
-		localContextFilePath:        exec_logger_constants.LOCAL_CONTEXT_FILE_NAME,
-		aliveFilePath:               exec_logger_constants.ALIVE_FILE_NAME,
-		exitedFilePath:              exec_logger_constants.EXITED_FILE_NAME,
-		mustAbortFilePath:           exec_logger_constants.MUST_ABORT_FILE_NAME,
-		recordResourceUsageFilePath: exec_logger_constants.RECORD_RESOURCE_USAGE_FILE_NAME,
-	}
-
-	return &commandExecer{
-		logger:              logger,
-		logFilePath:         exec_logger_constants.LOG_FILE_NAME,
-		stdErrIsError:       stdErrIsError,
-		timeoutKillDuration: timeoutKillDuration,
-		recordResourceUsage: recordResourceUsage,
-		runArgs:             runArgs,
-		statusHandler:       statusHandler,
-		stdioHandler:        nil, //Set inside `Run` method
+		localContextFilePath:        inRunDir(exec_logger_constants.LOCAL_CONTEXT_FILE_NAME),
+		aliveFilePath:               inRunDir(exec_logger_constants.ALIVE_FILE_NAME),
+		exitedFilePath:              inRunDir(exec_logger_constants.EXITED_FILE_NAME),
+		mustAbortFilePath:           inRunDir(exec_logger_constants.MUST_ABORT_FILE_NAME),
+		recordResourceUsageFilePath: inRunDir(exec_logger_constants.RECORD_RESOURCE_USAGE_FILE_NAME),
+		processesFilePath:           inRunDir(exec_logger_constants.PROCESSES_FILE_NAME),
+		diagnosticsFilePath:         inRunDir(exec_logger_constants.DIAGNOSTICS_FILE_NAME),
+		snapshotsFilePath:           inRunDir(exec_logger_constants.SNAPSHOTS_FILE_NAME),
+		historyFilePath:             inRunDir(exec_logger_constants.HISTORY_FILE_NAME),
+		resourceUsageCompactor:      exec_logger_dtos.NewResourceUsageCompactor(),
+		resourceSummaryAggregator:   exec_logger_dtos.NewResourceSummaryAggregator(),
+		recordIOMetrics:             options.recordIOMetrics,
+		redactor:                    options.redactor,
+	}
+	if options.recordProcesses {
+		statusHandler.processLifecycleTracker = exec_logger_dtos.NewProcessLifecycleTracker()
 	}
+
+	var metricsWriter *metricsTextfileWriter
+	if options.metricsTextfile != "" {
+		metricsWriter = &metricsTextfileWriter{
+			filePath: options.metricsTextfile,
+			labels:   options.metricsLabels,
+		}
+	}
+
+	c := &commandExecer{
+		execOptions:   options,
+		metricsWriter: metricsWriter,
+		logger:        logger,
+		logFilePath:   inRunDir(exec_logger_constants.LOG_FILE_NAME),
+		runArgs:       runArgs,
+		statusHandler: statusHandler,
+		stdioHandler:  nil, //Set inside `Run` method
+		abortRequest:  make(chan struct{}),
+
+		outputDrainTimeout: defaultOutputDrainTimeout,
+	}
+	c.webhookDeliveries = options.webhooks.NewDeliveries(func(webhookName string, err error) {
+		c.stdioHandler.writeFileLine(fmt.Sprintf("Webhook '%s' failed, error: %s", webhookName, err.Error()))
+	})
+	return c
 }
 
 type commandExecer struct {
-	logger              loggers.LoggerStdIO
-	logFilePath         string
-	stdErrIsError       bool
-	timeoutKillDuration time.Duration
-	recordResourceUsage bool
-	runArgs             []string
-	statusHandler       *execStatusHandler
-	stdioHandler        *stdioHandler
+	execOptions
+
+	logger        loggers.LoggerStdIO
+	logFilePath   string
+	runArgs       []string
+	statusHandler *execStatusHandler
+	stdioHandler  *stdioHandler
+	metricsWriter *metricsTextfileWriter
+	startTime     time.Time
+
+	abortMutex            sync.Mutex
+	abortOutcome          string
+	resourceLimitExceeded *exec_logger_dtos.ResourceLimitExceededDto
+	failedOutputActions   []string
+	abortRequest          chan struct{}
+	abortRequested        bool
+	outcome               string
+
+	//outputDrainTimeout is how long the output is still read after the command exited
+	outputDrainTimeout time.Duration
+
+	//webhookDeliveries are the deliveries of this run, the webhooks Notifier is shared with the other runs (of a batch, schedule or spool)
+	webhookDeliveries *webhooks.Deliveries
+}
+
+//RequestAbort aborts the running command like the must-abort file does, but without polling a file. It is safe to call more than once
+func (c *commandExecer) RequestAbort() {
+	c.abortMutex.Lock()
+	defer c.abortMutex.Unlock()
+	if !c.abortRequested {
+		c.abortRequested = true
+		close(c.abortRequest)
+	}
+}
+
+func (c *commandExecer) isAbortRequested() bool {
+	c.abortMutex.Lock()
+	defer c.abortMutex.Unlock()
+	return c.abortRequested
+}
+
+//getAbortRequest returns the channel that is closed by RequestAbort
+func (c *commandExecer) getAbortRequest() <-chan struct{} {
+	c.abortMutex.Lock()
+	defer c.abortMutex.Unlock()
+	return c.abortRequest
+}
+
+//resetAbortRequest consumes the abort request once it aborted a step, so it does not abort the always-run steps too
+func (c *commandExecer) resetAbortRequest() {
+	c.abortMutex.Lock()
+	defer c.abortMutex.Unlock()
+	if c.abortRequested {
+		c.abortRequested = false
+		c.abortRequest = make(chan struct{})
+	}
+}
+
+//Outcome returns the outcome written to the exited file, it is only set after Run
+func (c *commandExecer) Outcome() string {
+	c.abortMutex.Lock()
+	defer c.abortMutex.Unlock()
+	return c.outcome
+}
+
+//setAbortOutcome remembers why the process was aborted. Only the first reason is kept since that is the one that caused the kill
+func (c *commandExecer) setAbortOutcome(outcome string, limitExceeded *exec_logger_dtos.ResourceLimitExceededDto) {
+	c.abortMutex.Lock()
+	if c.abortOutcome != "" {
+		c.abortMutex.Unlock()
+		return
+	}
+	c.abortOutcome = outcome
+	c.resourceLimitExceeded = limitExceeded
+	c.abortMutex.Unlock()
+
+	switch outcome {
+	case exec_logger_dtos.ExitOutcomeTimedOut:
+		c.notifyLifecycleEvent(webhooks.EventTimedOut, "", nil)
+	case exec_logger_dtos.ExitOutcomeAborted:
+		c.notifyLifecycleEvent(webhooks.EventAborted, "", nil)
+	case exec_logger_dtos.ExitOutcomeResourceLimitExceeded:
+		c.notifyLifecycleEvent(webhooks.EventResourceLimitExceeded, limitExceeded.String(), nil)
+	}
+}
+
+//flushSinks writes the remaining buffered events to the sinks. The dropped events are only logged to the log file (and stderr)
+func (c *commandExecer) flushSinks() {
+	for _, report := range c.stdioHandler.sinks.Flush(sinksFlushTimeout) {
+		errStr := ""
+		if report.LastErr != nil {
+			errStr = fmt.Sprintf(", last error: %s", report.LastErr.Error())
+		}
+		msg := fmt.Sprintf("Sink '%s' dropped %d events%s", report.SinkName, report.Dropped, errStr)
+		c.stdioHandler.writeFileLine(msg)
+		c.logger.Err("%s", msg)
+	}
+	c.stdioHandler.sinks.Close()
+}
+
+//notifyLifecycleEvent writes the event to the sinks and sends it to the webhooks in the background.
+//Failed deliveries are only logged, they do not change the outcome
+func (c *commandExecer) notifyLifecycleEvent(event webhooks.Event, message string, exitStatus *exec_logger_dtos.ExitStatusDto) {
+	payload := &exec_logger_dtos.LifecycleEventDto{
+		Event:        string(event),
+		Time:         time.Now().UTC(),
+		Message:      message,
+		LocalContext: c.statusHandler.GetLocalContext(),
+		ExitStatus:   exitStatus,
+	}
+	if len(c.runArgs) > 0 {
+		payload.CommandLine = c.redactor.Redact(joinCommandLine(c.runArgs))
+	}
+	c.stdioHandler.sinks.WriteLifecycleEvent(payload)
+
+	c.webhookDeliveries.Notify(event, payload)
+}
+
+//resetAbortOutcome is called before every step of a multi-step job
+func (c *commandExecer) resetAbortOutcome() {
+	c.abortMutex.Lock()
+	defer c.abortMutex.Unlock()
+
+	c.abortOutcome = ""
+	c.resourceLimitExceeded = nil
+	c.failedOutputActions = nil
+}
+
+func (c *commandExecer) getAbortOutcome() (string, *exec_logger_dtos.ResourceLimitExceededDto) {
+	c.abortMutex.Lock()
+	defer c.abortMutex.Unlock()
+	return c.abortOutcome, c.resourceLimitExceeded
+}
+
+func (c *commandExecer) writeMetricsTextfile(metrics runMetrics) {
+	if c.metricsWriter == nil {
+		return
+	}
+
+	metrics.StartTime = c.startTime
+	metrics.Elapsed = time.Now().Sub(c.startTime)
+	metrics.StdoutLines, metrics.StderrLines = c.stdioHandler.lineCounts()
+	metrics.Summary = c.statusHandler.resourceSummaryAggregator.Summary()
+
+	if err := c.metricsWriter.write(metrics); err != nil {
+		c.stdioHandler.writeErrorLine(fmt.Sprintf("Cannot write metrics textfile, error: %s", err.Error()))
+	}
+}
+
+//captureTimeoutDiagnostics is called right before killing the process on timeout, to have evidence of why it hung
+func (c *commandExecer) captureTimeoutDiagnostics(pid int, timeoutKillDuration time.Duration) {
+	if c.timeoutDiagnostics {
+		c.stdioHandler.writeFileLine("Capturing diagnostics of the process tree")
+		dto := diagnostics.Capture(pid, fmt.Sprintf("Timeout of %s reached", timeoutKillDuration.String()))
+		if err := c.statusHandler.WriteDiagnostics(dto); err != nil {
+			c.stdioHandler.writeErrorLine(fmt.Sprintf("Cannot write diagnostics file, error: %s", err.Error()))
+		} else {
+			c.stdioHandler.writeFileLine(fmt.Sprintf("Wrote diagnostics of %d processes to '%s'", len(dto.Processes), c.statusHandler.diagnosticsFilePath))
+		}
+	}
+
+	if len(c.timeoutQuitSignalNames) > 0 {
+		signaled, warnings := diagnostics.SendQuitSignal(pid, c.timeoutQuitSignalNames)
+		for _, warning := range warnings {
+			c.stdioHandler.writeErrorLine(warning)
+		}
+		for _, p := range signaled {
+			c.stdioHandler.writeFileLine(fmt.Sprintf("Sent SIGQUIT to pid %d (%s)", p.Pid, p.Name))
+		}
+		if len(signaled) > 0 {
+			c.stdioHandler.writeFileLine(fmt.Sprintf("Waiting %s for the processes to write their thread dumps", c.timeoutQuitSignalWait.String()))
+			time.Sleep(c.timeoutQuitSignalWait)
+		}
+	}
+}
+
+//waitForOutput waits until all the output of the exited command was read. The pipes can still be open in processes started
+//by the command (that inherited them), so it stops waiting after the outputDrainTimeout and closes the pipes
+func (c *commandExecer) waitForOutput(wg *sync.WaitGroup, pipes ...*os.File) {
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(c.outputDrainTimeout):
+		for _, pipe := range pipes {
+			pipe.Close()
+		}
+		<-drained
+		c.stdioHandler.writeFileLine(fmt.Sprintf("The output was still open %s after the command exited (probably inherited by a child process), stopped reading it", c.outputDrainTimeout.String()))
+	}
 }
 
 func (c *commandExecer) abortProcess(cmd *exec.Cmd) {
@@ -83,26 +305,66 @@ func (c *commandExecer) cleanupBeforeStarting() error {
 			return fmt.Errorf("Cannot remove resource-usage file '%s', error: %s", c.statusHandler.recordResourceUsageFilePath, err.Error())
 		}
 	}
+	if err := os.Remove(c.statusHandler.processesFilePath); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("Cannot remove processes file '%s', error: %s", c.statusHandler.processesFilePath, err.Error())
+		}
+	}
+	if err := os.Remove(c.statusHandler.diagnosticsFilePath); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("Cannot remove diagnostics file '%s', error: %s", c.statusHandler.diagnosticsFilePath, err.Error())
+		}
+	}
+	if err := os.Remove(c.statusHandler.snapshotsFilePath); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("Cannot remove snapshots file '%s', error: %s", c.statusHandler.snapshotsFilePath, err.Error())
+		}
+	}
+	if len(c.tasks) > 0 {
+		//Otherwise the files of tasks that are skipped now would remain from an earlier run
+		tasksDir := filepath.Join(c.runDir, exec_logger_constants.TASKS_DIR_NAME)
+		if err := os.RemoveAll(tasksDir); err != nil {
+			return fmt.Errorf("Cannot remove tasks dir '%s', error: %s", tasksDir, err.Error())
+		}
+	}
 	return nil
 }
 
-func (c *commandExecer) runCommand() (exitCode int, returnErr error) {
-	if err := c.cleanupBeforeStarting(); err != nil {
-		return -1, err
-	}
+//commandSpec is a single command to run, either the whole job or one of its steps
+type commandSpec struct {
+	runArgs             []string
+	env                 []string
+	workingDir          string
+	timeoutKillDuration time.Duration
+}
 
-	cmd := exec.Command(c.runArgs[0], c.runArgs[1:]...)
+func (c *commandExecer) runCommand(spec commandSpec) (exitCode int, returnErr error) {
+	c.stdioHandler.resetCommandErrors()
 
-	stdout, err := cmd.StdoutPipe()
+	cmd := exec.Command(spec.runArgs[0], spec.runArgs[1:]...)
+	if len(spec.env) > 0 {
+		cmd.Env = append(os.Environ(), spec.env...)
+	}
+	cmd.Dir = spec.workingDir
+
+	//The pipes are created here instead of with cmd.StdoutPipe, which are closed by cmd.Wait, so the remaining output can still be read after the command exited
+	stdout, stdoutWriter, err := os.Pipe()
 	if err != nil {
 		return -1, err
 	}
-	stderr, err := cmd.StderrPipe()
+	defer stdout.Close()
+	stderr, stderrWriter, err := os.Pipe()
 	if err != nil {
+		stdoutWriter.Close()
 		return -1, err
 	}
+	defer stderr.Close()
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
 
 	err = cmd.Start()
+	stdoutWriter.Close()
+	stderrWriter.Close()
 	if err != nil {
 		return -1, err
 	}
@@ -115,19 +377,58 @@ func (c *commandExecer) runCommand() (exitCode int, returnErr error) {
 
 	c.stdioHandler.writeFileLine(fmt.Sprintf("Process started with PID %d", cmd.Process.Pid))
 
+	//The background goroutines stop once the command exited, so they do not act on the next command of a multi-step job
+	done := make(chan struct{})
+	var background sync.WaitGroup
+	defer func() {
+		close(done)
+		background.Wait()
+	}()
+	sleepUnlessDone := func(d time.Duration) bool {
+		select {
+		case <-done:
+			return false
+		case <-time.After(d):
+			return true
+		}
+	}
+	//The process tree is gone once cmd.Wait returned, so sampling stops then instead of after the output was drained
+	waitReturned := make(chan struct{})
+
+	background.Add(1)
 	go func(sh *execStatusHandler) {
+		defer background.Done()
 		for {
 			if tmpErr := sh.WriteAlive(); tmpErr != nil {
 				c.stdioHandler.writeErrorLine(fmt.Sprintf("Cannot write alive file, error: %s", tmpErr.Error()))
 			}
-			time.Sleep(2 * time.Second)
+			if !sleepUnlessDone(2 * time.Second) {
+				return
+			}
 		}
 	}(c.statusHandler)
 
 	procID := cmd.Process.Pid
-	if c.recordResourceUsage {
-		c.stdioHandler.writeFileLine("Starting to record resource usage")
+	if c.mustSampleResourceUsage() {
+		if c.recordResourceUsage {
+			c.stdioHandler.writeFileLine("Starting to record resource usage")
+		}
+		if c.recordProcesses {
+			c.stdioHandler.writeFileLine("Starting to record process lifecycle events")
+		}
+		if c.resourceLimits.isSet() {
+			c.stdioHandler.writeFileLine("Starting to enforce resource limits")
+		}
+
+		//The summary also has the CPU time of the earlier steps of a job, the -max-cpu-time is per step
+		cpuSecondsBeforeCommand := 0
+		if summary := c.statusHandler.resourceSummaryAggregator.Summary(); summary != nil {
+			cpuSecondsBeforeCommand = summary.TotalCPUSeconds
+		}
+
+		background.Add(1)
 		go func(sh *execStatusHandler) {
+			defer background.Done()
 			iterationsPerDuration := 10
 			durationList := []time.Duration{
 				500 * time.Millisecond,
@@ -135,30 +436,68 @@ func (c *commandExecer) runCommand() (exitCode int, returnErr error) {
 				10 * time.Second,
 				30 * time.Second,
 			}
+			if c.resourceLimits.isSet() {
+				//Sampling too seldom means a limit is only noticed long after it was exceeded
+				durationList = durationList[:2]
+			}
 			durationIncreaser := sleep_durations.New(iterationsPerDuration, durationList)
 
 			for {
-				if tmpErr := sh.WriteResourceUsage(procID); tmpErr != nil {
-					c.stdioHandler.writeErrorLine(fmt.Sprintf("Cannot write resource-usage file, error: %s", tmpErr.Error()))
+				sample, warnings, tmpErr := sh.SampleResourceUsage(procID, c.recordResourceUsage)
+				for _, warning := range warnings {
+					c.stdioHandler.writeWarningLine(warning)
+				}
+				if tmpErr != nil {
+					c.stdioHandler.writeErrorLine(fmt.Sprintf("Cannot sample resource usage, error: %s", tmpErr.Error()))
+				}
+				c.writeMetricsTextfile(runMetrics{Sample: sample})
+
+				cpuSeconds := 0
+				if summary := sh.resourceSummaryAggregator.Summary(); summary != nil {
+					cpuSeconds = summary.TotalCPUSeconds - cpuSecondsBeforeCommand
+				}
+				if limitExceeded := c.resourceLimits.checkExceeded(sample, cpuSeconds); limitExceeded != nil {
+					c.stdioHandler.writeFileLine(fmt.Sprintf("Resource limit %s, now aborting", limitExceeded.String()))
+					c.setAbortOutcome(exec_logger_dtos.ExitOutcomeResourceLimitExceeded, limitExceeded)
+					c.abortProcess(cmd)
+					break
+				}
+				select {
+				case <-waitReturned:
+					return
+				case <-done:
+					return
+				case <-time.After(durationIncreaser.Next()):
 				}
-				time.Sleep(durationIncreaser.Next())
 			}
 		}(c.statusHandler)
 	}
 
+	background.Add(1)
 	go func(sh *execStatusHandler) {
+		defer background.Done()
+		abortRequest := c.getAbortRequest()
 		for {
 			if mustAbort, checkErr := sh.CheckMustAbort(); checkErr != nil {
 				c.stdioHandler.writeFileLine(fmt.Sprintf("Unable to check for abort request, error: %s", checkErr.Error()))
-			} else if mustAbort {
+			} else if mustAbort || c.isAbortRequested() {
 				c.stdioHandler.writeFileLine("Got ABORT message")
+				c.setAbortOutcome(exec_logger_dtos.ExitOutcomeAborted, nil)
 				c.abortProcess(cmd)
 				break
 			}
-			time.Sleep(2 * time.Second)
+			select {
+			case <-done:
+				return
+			case <-abortRequest:
+			case <-time.After(2 * time.Second):
+			}
 		}
 	}(c.statusHandler)
 
+	c.stdioHandler.onOutputTrigger = func(trigger *output_actions.Trigger, line string) {
+		c.runOutputAction(cmd, trigger, line)
+	}
 	c.stdioHandler.stdoutScanner = bufio.NewScanner(stdout)
 	c.stdioHandler.stderrScanner = bufio.NewScanner(stderr)
 
@@ -167,42 +506,62 @@ func (c *commandExecer) runCommand() (exitCode int, returnErr error) {
 	go c.stdioHandler.startScanningStdout(&wg)
 	go c.stdioHandler.startScanningStderr(&wg)
 
+	waitForExit := func() error {
+		err := cmd.Wait()
+		close(waitReturned)
+		c.waitForOutput(&wg, stdout, stderr)
+		return err
+	}
+
 	var waitErr error
 	timeoutOccurred := false
-	if c.timeoutKillDuration > 0 {
-		c.stdioHandler.writeFileLine(fmt.Sprintf("Using timeout of '%s' for process", c.timeoutKillDuration.String()))
+	if spec.timeoutKillDuration > 0 {
+		c.stdioHandler.writeFileLine(fmt.Sprintf("Using timeout of '%s' for process", spec.timeoutKillDuration.String()))
 
-		done := make(chan error)
-		go func() { done <- cmd.Wait() }()
+		exited := make(chan error, 1)
+		go func() { exited <- waitForExit() }()
 		select {
-		case waitErr = <-done:
-			waitErr = waitErr
-		case <-time.After(c.timeoutKillDuration):
-			c.stdioHandler.writeFileLine(fmt.Sprintf("Timeout of %s reached, now aborting", c.timeoutKillDuration.String()))
+		case waitErr = <-exited:
+		case <-time.After(spec.timeoutKillDuration):
+			c.stdioHandler.writeFileLine(fmt.Sprintf("Timeout of %s reached, now aborting", spec.timeoutKillDuration.String()))
+			c.captureTimeoutDiagnostics(procID, spec.timeoutKillDuration)
+			c.setAbortOutcome(exec_logger_dtos.ExitOutcomeTimedOut, nil)
 			c.abortProcess(cmd)
 			timeoutOccurred = true
+			<-exited
 		}
 	} else {
 		c.stdioHandler.writeFileLine("No timeout set for process")
-		waitErr = cmd.Wait()
+		waitErr = waitForExit()
 	}
 
 	//TODO: Just give things time to cool down, like writing of the "Successfully killed process" log. This can however be improved with a WaitGroup
 	time.Sleep(500 * time.Millisecond)
 
+	if _, limitExceeded := c.getAbortOutcome(); limitExceeded != nil {
+		return -1, fmt.Errorf("The command exceeded the resource limit %s", limitExceeded.String())
+	}
+
 	if waitErr != nil {
 		if exitCode, ok := getExitCodeFromError(waitErr); ok {
 			return exitCode, waitErr
 		}
 		return -1, waitErr
 	}
-	wg.Wait()
 
 	if timeoutOccurred {
-		return -1, fmt.Errorf("The command timed out after '%s'", c.timeoutKillDuration.String())
+		return -1, fmt.Errorf("The command timed out after '%s'", spec.timeoutKillDuration.String())
 	}
 
-	if c.stdioHandler.commandHadStdErr && c.stdErrIsError {
+	if failedOutputActions := c.getFailedOutputActions(); len(failedOutputActions) > 0 {
+		return -1, fmt.Errorf("The command finished running but the output triggered the fail action(s) %s.", strings.Join(failedOutputActions, ", "))
+	}
+
+	if errorMatchCount := c.stdioHandler.getErrorMatchCount(); errorMatchCount > 0 {
+		return -1, fmt.Errorf("The command finished running but %d output lines matched error rules.", errorMatchCount)
+	}
+
+	if c.stdioHandler.hadStdErr() && c.stdErrIsError {
 		return -1, fmt.Errorf("The command finished running but had error lines (written to stderr).")
 	}
 
@@ -210,32 +569,89 @@ func (c *commandExecer) runCommand() (exitCode int, returnErr error) {
 }
 
 func (c *commandExecer) Run() (exitCode int, returnErr error) {
-	err := os.Remove(c.logFilePath)
-	if err != nil && !os.IsNotExist(err) {
-		return -1, fmt.Errorf("Failure to remove log file, error: %s", err.Error())
-	}
-
 	parentDir := filepath.Dir(c.logFilePath)
 	if err := os.MkdirAll(parentDir, 0755); err != nil {
 		return -1, fmt.Errorf("Unable to create parent dir '%s' of log file, error: %s", parentDir, err.Error())
 	}
 
-	logFile, err := os.OpenFile(c.logFilePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0655)
+	logFile, err := c.openLogWriter(c.logFilePath)
 	if err != nil {
-		return -1, fmt.Errorf("Failure to open log file '%s' for writing, error; %s", c.logFilePath, err.Error())
+		return -1, err
+	}
+	defer func() {
+		if err := logFile.Close(); err != nil {
+			c.logger.Err("Cannot close log file '%s', error: %s", c.logFilePath, err.Error())
+		}
+	}()
+	if c.logFsync != logFsyncNone {
+		defer func() {
+			if err := logFile.Sync(); err != nil {
+				c.logger.Err("Cannot fsync log file '%s', error: %s", c.logFilePath, err.Error())
+			}
+		}()
 	}
-	defer logFile.Close()
 
 	c.stdioHandler = &stdioHandler{
-		logger: c.logger,
-		writer: logFile,
+		logger:      c.logger,
+		sinks:       newLogFileSinks(c.logger, logFile, c.logFsync == logFsyncLine),
+		redactor:    c.redactor,
+		outputRules: c.outputRules,
+
+		outputTriggers: c.outputTriggers,
 	}
+	if c.outputRules != nil {
+		c.stdioHandler.matchSummary = log_patterns.NewMatchSummary(c.outputRules)
+	}
+	//The sinks that can not be opened are only logged after the version line, they do not change the outcome
+	sinkOpenErrors := []string{}
+	c.sinks.AddTo(c.stdioHandler.sinks, func(sinkName string, err error) {
+		sinkOpenErrors = append(sinkOpenErrors, fmt.Sprintf("Cannot open sink '%s', error: %s", sinkName, err.Error()))
+	})
+	defer c.flushSinks()
 
-	startTime := time.Now()
+	c.startTime = time.Now()
 
 	c.stdioHandler.writeFileLine(fmt.Sprintf("Exec-logger version %s", Version))
-	c.stdioHandler.writeFileLine(fmt.Sprintf("Calling commandline: %s", joinCommandLine(c.runArgs)))
-	exitCode, err = c.runCommand()
+	for _, msg := range sinkOpenErrors {
+		c.stdioHandler.writeFileLine(msg)
+		c.logger.Err("%s", msg)
+	}
+	if c.incarnation > 0 {
+		c.stdioHandler.writeFileLine(fmt.Sprintf("===== Supervised incarnation %d started =====", c.incarnation))
+	}
+	if len(c.steps) > 0 {
+		c.stdioHandler.writeFileLine(fmt.Sprintf("Running job with %d steps", len(c.steps)))
+	} else if len(c.tasks) > 0 {
+		c.stdioHandler.writeFileLine(fmt.Sprintf("Running job with %d tasks, at most %d at the same time", len(c.tasks), c.taskConcurrency))
+	} else {
+		c.stdioHandler.writeFileLine(fmt.Sprintf("Calling commandline: %s", joinCommandLine(c.runArgs)))
+	}
+	if c.workingDir != "" {
+		c.stdioHandler.writeFileLine(fmt.Sprintf("Using working directory: %s", c.workingDir))
+	}
+
+	c.notifyLifecycleEvent(webhooks.EventStarted, "", nil)
+
+	var steps []*exec_logger_dtos.StepStatusDto
+	var tasks []*exec_logger_dtos.TaskStatusDto
+	if err = c.cleanupBeforeStarting(); err != nil {
+		exitCode = -1
+	} else if len(c.steps) > 0 {
+		exitCode, err, steps = c.runSteps()
+	} else if len(c.tasks) > 0 {
+		exitCode, err, tasks = c.runTasks()
+	} else {
+		exitCode, err = c.runCommand(commandSpec{
+			runArgs:             c.runArgs,
+			env:                 c.env,
+			workingDir:          c.workingDir,
+			timeoutKillDuration: c.timeoutKillDuration,
+		})
+		if tmpErr := c.statusHandler.FinishProcessLifecycle(time.Now()); tmpErr != nil {
+			c.stdioHandler.writeErrorLine(fmt.Sprintf("Cannot write process lifecycle exit events, error: %s", tmpErr.Error()))
+		}
+	}
+	c.stdioHandler.writeMatchSummary()
 
 	exitCodeMsg := fmt.Sprintf("Command exited with code %d", exitCode)
 	if exitCode != 0 {
@@ -244,8 +660,34 @@ func (c *commandExecer) Run() (exitCode int, returnErr error) {
 		c.stdioHandler.writeFileLine(exitCodeMsg)
 	}
 
-	totalDuration := time.Now().Sub(startTime)
-	c.statusHandler.WriteExitedJson(exitCode, err, totalDuration)
+	outcome, limitExceeded := c.getAbortOutcome()
+	if len(steps) > 0 {
+		outcome, limitExceeded = exec_logger_dtos.GetJobOutcome(steps)
+	} else if len(tasks) > 0 {
+		outcome, limitExceeded = exec_logger_dtos.GetTasksOutcome(tasks), nil
+	}
+	if outcome == "" {
+		if err != nil {
+			outcome = exec_logger_dtos.ExitOutcomeFailed
+		} else {
+			outcome = exec_logger_dtos.ExitOutcomeSuccess
+		}
+	}
+
+	c.abortMutex.Lock()
+	c.outcome = outcome
+	c.abortMutex.Unlock()
+
+	totalDuration := time.Now().Sub(c.startTime)
+	exitStatus, writeErr := c.statusHandler.WriteExitedJson(exitCode, err, totalDuration, outcome, limitExceeded, steps, tasks)
+	if writeErr != nil {
+		c.stdioHandler.writeErrorLine(fmt.Sprintf("Cannot write exited file, error: %s", writeErr.Error()))
+	}
+	c.writeMetricsTextfile(runMetrics{Exited: true, ExitCode: exitCode, Outcome: outcome})
+
+	c.notifyLifecycleEvent(webhooks.EventFinished, "", exitStatus)
+	//The log must stay open for the failed deliveries
+	c.webhookDeliveries.Wait()
 
 	c.stdioHandler.writeFileLine(fmt.Sprintf("Total duration was %s", totalDuration.String()))
 	if err != nil {
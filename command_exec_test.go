@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCommandExecer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The commands use sh")
+	}
+
+	Convey("Testing commandExecer.Run", t, func() {
+		runDir, err := ioutil.TempDir("", "exec-logger-test-")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(runDir)
+
+		Convey("A child process that keeps the output open does not block the exit", func() {
+			execer := NewCommandExecer(NewStdioLogger(), execOptions{runDir: runDir}, []string{"sh", "-c", "sleep 8 & echo last line"})
+			execer.outputDrainTimeout = 500 * time.Millisecond
+
+			startTime := time.Now()
+			exitCode, err := execer.Run()
+			So(err, ShouldBeNil)
+			So(exitCode, ShouldEqual, 0)
+			So(time.Now().Sub(startTime), ShouldBeLessThan, 4*time.Second)
+
+			logged, err := ioutil.ReadFile(execer.logFilePath)
+			So(err, ShouldBeNil)
+			So(string(logged), ShouldContainSubstring, "] last line")
+			So(string(logged), ShouldContainSubstring, "The output was still open")
+		})
+
+		Convey("The always-run steps still run after RequestAbort aborted a step", func() {
+			options := execOptions{
+				runDir: runDir,
+				steps: []*jobStep{
+					&jobStep{name: "build", runArgs: []string{"sleep", "20"}},
+					&jobStep{name: "test", runArgs: []string{"echo", "test"}},
+					&jobStep{name: "cleanup", runArgs: []string{"echo", "cleanup"}, alwaysRun: true},
+				},
+			}
+			execer := NewCommandExecer(NewStdioLogger(), options, nil)
+			go func() {
+				time.Sleep(time.Second)
+				execer.RequestAbort()
+			}()
+
+			_, err := execer.Run()
+			So(err, ShouldNotBeNil)
+			So(execer.isAbortRequested(), ShouldBeFalse)
+
+			logged, err := ioutil.ReadFile(execer.logFilePath)
+			So(err, ShouldBeNil)
+			So(string(logged), ShouldContainSubstring, "===== Step 1/3 'build' finished with exit code -1 (aborted)")
+			So(string(logged), ShouldContainSubstring, "===== Skipping step 2/3 'test' =====")
+			So(string(logged), ShouldContainSubstring, "] cleanup")
+			So(string(logged), ShouldContainSubstring, "===== Step 3/3 'cleanup' finished with exit code 0 (success)")
+		})
+	})
+}
@@ -2,50 +2,109 @@ package main
 
 import (
 	"bufio"
-	"os"
+	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/go-zero-boilerplate/loggers"
 	"github.com/golang-devops/exec-logger/exec_logger_constants"
+	"github.com/golang-devops/exec-logger/log_patterns"
+	"github.com/golang-devops/exec-logger/log_segments"
 )
 
 var (
-	errorLogLinePattern     = regexp.MustCompile(`\[[0-9]{4}-[0-9]{2}-[0-9]{2} [0-9]{2}:[0-9]{2}:[0-9]{2}\] EASY_EXEC_ERROR: (.*)`)
-	additionalErrorPatterns []*regexp.Regexp
+	errorLogLinePattern = regexp.MustCompile(`\[[0-9]{4}-[0-9]{2}-[0-9]{2} [0-9]{2}:[0-9]{2}:[0-9]{2}\] EASY_EXEC_ERROR: (.*)`)
 )
 
-func lineHasError(line string) bool {
-	if errorLogLinePattern.MatchString(line) {
-		return true
+//buildParseLogRuleSet combines the pattern rules with lastly the built-in pattern of exec-logger error lines.
+//Since the first matching rule wins, exclude rules in the patterns file can also suppress the built-in one
+func buildParseLogRuleSet(stdioLogger loggers.LoggerStdIO, patternsFilePath, parsePatterns string) (*log_patterns.RuleSet, error) {
+	ruleSet, err := loadPatternRules(stdioLogger, patternsFilePath, parsePatterns)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, ap := range additionalErrorPatterns {
-		if ap.MatchString(line) {
-			return true
+	ruleSet.Add(&log_patterns.Rule{
+		Name:     "exec-logger-error",
+		Pattern:  errorLogLinePattern.String(),
+		Severity: log_patterns.SeverityError,
+	})
+
+	if err := ruleSet.Compile(); err != nil {
+		return nil, err
+	}
+	return ruleSet, nil
+}
+
+func writeMatchSummary(stdioLogger loggers.LoggerStdIO, summary *log_patterns.MatchSummary) {
+	stdioLogger.Out("Summary of pattern matches:")
+	for _, c := range summary.Counts() {
+		kind := string(c.Rule.Severity)
+		if c.Rule.Exclude {
+			kind = "exclude"
 		}
+		stdioLogger.Out("  %-8s %s: %d", kind, c.Rule.Name, c.Count)
 	}
+}
 
-	return false
+//parseLogOptions are the filters of the parselog task
+type parseLogOptions struct {
+	ruleSet      *log_patterns.RuleSet
+	contextLines int
+	since        time.Time
+	until        time.Time
+	errorsOnly   bool
 }
 
-func handleParseLogToStdioCommand(stdioLogger loggers.LoggerStdIO) error {
-	logFile, err := os.Open(exec_logger_constants.LOG_FILE_NAME)
+//handleParseLogToStdioCommand streams the log file and returns the number of lines (inside the time window) that matched error rules
+func handleParseLogToStdioCommand(stdioLogger loggers.LoggerStdIO, options parseLogOptions) (int, error) {
+	logFile, err := log_segments.OpenReader(exec_logger_constants.LOG_FILE_NAME)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer logFile.Close()
 
 	scanner := bufio.NewScanner(logFile)
 	scanner.Split(bufio.ScanLines)
 
+	ruleSet := options.ruleSet
+	summary := log_patterns.NewMatchSummary(ruleSet)
+	window := &logTimeWindow{since: options.since, until: options.until}
+	printer := &contextPrinter{
+		contextLines: options.contextLines,
+		printLine: func(line parsedLogLine) {
+			if line.isError {
+				stdioLogger.Err("%s", line.text)
+			} else {
+				stdioLogger.Out("%s", line.text)
+			}
+		},
+		printSeparator: func() { stdioLogger.Out("--") },
+	}
+
+	lineNumber := 0
 	for scanner.Scan() {
+		lineNumber++
 		txt := scanner.Text()
-		if lineHasError(txt) {
-			stdioLogger.Err("%s", txt)
-		} else {
-			stdioLogger.Out("%s", txt)
+
+		inside, pastUntil := window.check(txt)
+		if pastUntil {
+			break
+		}
+		if !inside {
+			continue
 		}
+
+		rule := ruleSet.Match(txt)
+		summary.Add(rule)
+
+		isError := rule != nil && !rule.Exclude && rule.Severity == log_patterns.SeverityError
+		printer.add(parsedLogLine{lineNumber: lineNumber, text: txt, isError: isError}, isError || !options.errorsOnly)
+	}
+	if err = scanner.Err(); err != nil {
+		return 0, fmt.Errorf("Cannot read log file, error: %s", err.Error())
 	}
 
-	return nil
+	writeMatchSummary(stdioLogger, summary)
+	return summary.CountBySeverity(log_patterns.SeverityError), nil
 }
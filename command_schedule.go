@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/go-zero-boilerplate/loggers"
+
+	"github.com/golang-devops/exec-logger/exec_logger_constants"
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/job_config"
+	"github.com/golang-devops/exec-logger/schedules"
+)
+
+//scheduledJob is a schedule with the state of its runs
+type scheduledJob struct {
+	schedule *schedules.Schedule
+	state    *exec_logger_dtos.ScheduledJobStateDto
+	//scheduledTime is the next time of the schedule without the jitter, the next times are calculated from it so they do not drift
+	scheduledTime time.Time
+	running       *commandExecer
+}
+
+type scheduledRunResult struct {
+	job      *scheduledJob
+	exitCode int
+	err      error
+}
+
+//scheduler runs the jobs of the schedules, each in its own run directory
+type scheduler struct {
+	logger        loggers.LoggerStdIO
+	options       execOptions
+	stateFilePath string
+
+	state    *exec_logger_dtos.ScheduleStateDto
+	jobs     []*scheduledJob
+	results  chan scheduledRunResult
+	stopping bool
+}
+
+func newScheduler(logger loggers.LoggerStdIO, options execOptions, scheduleFile *schedules.ScheduleFile, scheduleDir string) *scheduler {
+	s := &scheduler{
+		logger:        logger,
+		options:       options,
+		stateFilePath: filepath.Join(scheduleDir, exec_logger_constants.SCHEDULE_STATE_FILE_NAME),
+		state:         &exec_logger_dtos.ScheduleStateDto{StartTime: time.Now()},
+		results:       make(chan scheduledRunResult, len(scheduleFile.Schedules)),
+	}
+	for _, schedule := range scheduleFile.Schedules {
+		job := &scheduledJob{
+			schedule: schedule,
+			state: &exec_logger_dtos.ScheduledJobStateDto{
+				Name:   schedule.Name,
+				RunDir: filepath.Join(scheduleDir, schedule.Name),
+			},
+		}
+		s.jobs = append(s.jobs, job)
+		s.state.Schedules = append(s.state.Schedules, job.state)
+	}
+	return s
+}
+
+func (s *scheduler) writeState() {
+	jsonBytes, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		s.logger.Err("Cannot marshal schedule state to json, error: %s", err.Error())
+		return
+	}
+	if err = writeFileAtomic(s.stateFilePath, jsonBytes, 0600); err != nil {
+		s.logger.Err("Cannot write schedule state file '%s', error: %s", s.stateFilePath, err.Error())
+	}
+}
+
+//scheduleNext sets the next run of the job after `after`. Runs missed while the previous time was in the past are not caught up
+func (s *scheduler) scheduleNext(job *scheduledJob, after time.Time) {
+	now := time.Now()
+	job.scheduledTime = job.schedule.NextRun(after)
+	if !job.scheduledTime.IsZero() && job.scheduledTime.Before(now) {
+		job.scheduledTime = job.schedule.NextRun(now)
+	}
+
+	job.state.NextRunTime = time.Time{}
+	if !job.scheduledTime.IsZero() {
+		job.state.NextRunTime = job.scheduledTime.Add(job.schedule.GetJitter())
+	}
+}
+
+func (s *scheduler) start(job *scheduledJob) {
+	options := s.options
+	options.runDir = job.state.RunDir
+	options.env = append(append([]string{}, s.options.env...), (&job_config.Config{Env: job.schedule.Env}).EnvList()...)
+	if job.schedule.WorkingDir != "" {
+		options.workingDir = job.schedule.WorkingDir
+	}
+	if timeoutKill := job.schedule.GetTimeoutKill(); timeoutKill > 0 {
+		options.timeoutKillDuration = timeoutKill
+	}
+	if options.outputTriggers != nil {
+		options.outputTriggers = options.outputTriggers.Clone()
+	}
+
+	runArgs := (&job_config.Config{Command: job.schedule.Command, Shell: job.schedule.Shell}).RunArgs()
+	execer := NewCommandExecer(s.logger, options, runArgs)
+
+	job.running = execer
+	job.state.Running = true
+	job.state.Queued = false
+	job.state.RunCount++
+	job.state.LastRunTime = time.Now()
+	s.logger.Out("Starting run %d of schedule '%s' in '%s'", job.state.RunCount, job.schedule.Name, job.state.RunDir)
+
+	go func() {
+		exitCode, err := execer.Run()
+		s.results <- scheduledRunResult{job: job, exitCode: exitCode, err: err}
+	}()
+}
+
+func (s *scheduler) finish(result scheduledRunResult) {
+	job := result.job
+	job.state.Running = false
+	job.state.LastExitTime = time.Now()
+	job.state.LastDuration = job.state.LastExitTime.Sub(job.state.LastRunTime).String()
+	job.state.LastExitCode = result.exitCode
+	job.state.LastOutcome = job.running.Outcome()
+	job.state.LastError = ""
+	if result.err != nil {
+		job.state.LastError = s.options.redactor.Redact(result.err.Error())
+	}
+	job.running = nil
+	s.logger.Out("Run %d of schedule '%s' finished with exit code %d (%s) in %s", job.state.RunCount, job.schedule.Name, result.exitCode, job.state.LastOutcome, job.state.LastDuration)
+
+	if job.state.Queued && !s.stopping {
+		s.start(job)
+	}
+}
+
+//runDue starts the run of the job that is due now, or applies the overlap policy if the previous run is still running
+func (s *scheduler) runDue(job *scheduledJob) {
+	s.scheduleNext(job, job.scheduledTime)
+
+	if job.running == nil {
+		s.start(job)
+		return
+	}
+
+	switch job.schedule.Overlap {
+	case schedules.OverlapQueue:
+		if !job.state.Queued {
+			job.state.Queued = true
+			s.logger.Out("Schedule '%s' is still running, queued the next run", job.schedule.Name)
+			return
+		}
+	case schedules.OverlapKillPrevious:
+		job.state.Queued = true
+		s.logger.Out("Schedule '%s' is still running, aborting it to start the next run", job.schedule.Name)
+		job.running.RequestAbort()
+		return
+	}
+
+	job.state.SkippedCount++
+	s.logger.Out("Schedule '%s' is still running, skipped the run", job.schedule.Name)
+}
+
+//run runs the schedules until a stop signal is received or none of them will run again
+func (s *scheduler) run(stopSignals chan os.Signal) {
+	for _, job := range s.jobs {
+		s.scheduleNext(job, time.Now())
+		s.logger.Out("Schedule '%s' first runs at %s", job.schedule.Name, job.state.NextRunTime.Format(exec_logger_constants.LOG_LINE_TIME_FORMAT))
+	}
+
+	for {
+		now := time.Now()
+		waitDuration := time.Hour
+		runningCount := 0
+		for _, job := range s.jobs {
+			if !job.state.NextRunTime.IsZero() && !job.state.NextRunTime.After(now) {
+				s.runDue(job)
+			}
+			if job.running != nil {
+				runningCount++
+			}
+			if !job.state.NextRunTime.IsZero() && job.state.NextRunTime.Sub(now) < waitDuration {
+				waitDuration = job.state.NextRunTime.Sub(now)
+			}
+		}
+		s.writeState()
+
+		if runningCount == 0 && waitDuration == time.Hour && s.allFinished() {
+			s.logger.Out("None of the schedules will run again")
+			return
+		}
+
+		select {
+		case result := <-s.results:
+			s.finish(result)
+		case <-time.After(waitDuration):
+		case sig := <-stopSignals:
+			s.stop(sig, runningCount)
+			return
+		}
+	}
+}
+
+func (s *scheduler) allFinished() bool {
+	for _, job := range s.jobs {
+		if !job.state.NextRunTime.IsZero() || job.running != nil {
+			return false
+		}
+	}
+	return true
+}
+
+//stop aborts the running jobs and waits for them to finish
+func (s *scheduler) stop(sig os.Signal, runningCount int) {
+	s.logger.Out("Got signal %s, aborting the %d running schedules", sig.String(), runningCount)
+	s.stopping = true
+	for _, job := range s.jobs {
+		if job.running != nil {
+			job.running.RequestAbort()
+		}
+	}
+	for ; runningCount > 0; runningCount-- {
+		s.finish(<-s.results)
+	}
+	for _, job := range s.jobs {
+		job.state.Queued = false
+		job.state.NextRunTime = time.Time{}
+	}
+	s.writeState()
+}
+
+//handleScheduleCommand runs the schedules of the schedule file until the process gets an interrupt or terminate signal
+func handleScheduleCommand(logger loggers.LoggerStdIO, options execOptions, scheduleFilePath, scheduleDir string) error {
+	scheduleFile := &schedules.ScheduleFile{}
+	if err := job_config.LoadDefinitions(scheduleFilePath, scheduleFile); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(scheduleDir, 0755); err != nil {
+		return fmt.Errorf("Cannot create schedule dir '%s', error: %s", scheduleDir, err.Error())
+	}
+
+	rand.Seed(time.Now().UnixNano())
+
+	stopSignals := make(chan os.Signal, 1)
+	signal.Notify(stopSignals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stopSignals)
+
+	logger.Out("Running %d schedules in '%s'", len(scheduleFile.Schedules), scheduleDir)
+	newScheduler(logger, options, scheduleFile, scheduleDir).run(stopSignals)
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/exec_logger_constants"
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/job_config"
+	"github.com/golang-devops/exec-logger/schedules"
+)
+
+func TestScheduler(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The schedules run sh")
+	}
+
+	Convey("Testing the scheduler", t, func() {
+		scheduleDir, err := ioutil.TempDir("", "exec-logger-test-schedule-")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(scheduleDir)
+
+		newJob := func(overlap schedules.OverlapPolicy) (*scheduler, *scheduledJob) {
+			scheduleFile := &schedules.ScheduleFile{}
+			err := job_config.ParseDefinitions("schedules.json", []byte(`{
+				"Schedules": [{"Name": "sync", "Every": "1h", "Shell": "echo synced", "Overlap": "`+string(overlap)+`"}]
+			}`), scheduleFile)
+			So(err, ShouldBeNil)
+			s := newScheduler(NewStdioLogger(), execOptions{}, scheduleFile, scheduleDir)
+			job := s.jobs[0]
+			s.scheduleNext(job, time.Now())
+			return s, job
+		}
+		//The fake running execer is never started, it only records the abort request. The execers are only compared as pointers
+		//since rendering a running execer in an assertion would race with it
+		newRunningExecer := func() *commandExecer {
+			return NewCommandExecer(NewStdioLogger(), execOptions{}, []string{"sleep", "60"})
+		}
+
+		Convey("A due job that is not running is started", func() {
+			s, job := newJob(schedules.OverlapSkip)
+			s.runDue(job)
+			So(job.running != nil, ShouldBeTrue)
+			So(job.state.Running, ShouldBeTrue)
+			So(job.state.RunCount, ShouldEqual, 1)
+
+			s.finish(<-s.results)
+			So(job.running == nil, ShouldBeTrue)
+			So(job.state.Running, ShouldBeFalse)
+			So(job.state.LastExitCode, ShouldEqual, 0)
+			So(job.state.LastOutcome, ShouldEqual, exec_logger_dtos.ExitOutcomeSuccess)
+
+			logged, err := ioutil.ReadFile(filepath.Join(job.state.RunDir, exec_logger_constants.LOG_FILE_NAME))
+			So(err, ShouldBeNil)
+			So(string(logged), ShouldContainSubstring, "] synced")
+		})
+
+		Convey("With the skip policy the run is skipped while the previous one runs", func() {
+			s, job := newJob(schedules.OverlapSkip)
+			running := newRunningExecer()
+			job.running = running
+			nextRunTime := job.state.NextRunTime
+
+			s.runDue(job)
+			So(job.running == running, ShouldBeTrue)
+			So(job.state.SkippedCount, ShouldEqual, 1)
+			So(job.state.Queued, ShouldBeFalse)
+			So(running.isAbortRequested(), ShouldBeFalse)
+			So(job.state.NextRunTime.After(nextRunTime), ShouldBeTrue)
+		})
+
+		Convey("With the queue policy one run is queued and started when the previous one finished", func() {
+			s, job := newJob(schedules.OverlapQueue)
+			running := newRunningExecer()
+			job.running = running
+
+			s.runDue(job)
+			So(job.state.Queued, ShouldBeTrue)
+			So(job.state.SkippedCount, ShouldEqual, 0)
+			s.runDue(job)
+			So(job.state.SkippedCount, ShouldEqual, 1)
+			So(running.isAbortRequested(), ShouldBeFalse)
+
+			s.finish(scheduledRunResult{job: job, exitCode: 0})
+			So(job.state.Queued, ShouldBeFalse)
+			So(job.state.Running, ShouldBeTrue)
+			So(job.state.RunCount, ShouldEqual, 1)
+			So(job.running != nil, ShouldBeTrue)
+			So(job.running != running, ShouldBeTrue)
+
+			s.finish(<-s.results)
+			So(job.running == nil, ShouldBeTrue)
+			So(job.state.LastOutcome, ShouldEqual, exec_logger_dtos.ExitOutcomeSuccess)
+		})
+
+		Convey("With the kill-previous policy the previous run is aborted and the next one queued", func() {
+			s, job := newJob(schedules.OverlapKillPrevious)
+			running := newRunningExecer()
+			job.running = running
+
+			s.runDue(job)
+			So(running.isAbortRequested(), ShouldBeTrue)
+			So(job.state.Queued, ShouldBeTrue)
+			So(job.state.SkippedCount, ShouldEqual, 0)
+
+			s.finish(scheduledRunResult{job: job, exitCode: -1})
+			So(job.state.LastExitCode, ShouldEqual, -1)
+			So(job.state.Running, ShouldBeTrue)
+			s.finish(<-s.results)
+			So(job.state.RunCount, ShouldEqual, 1)
+		})
+
+		Convey("Stopping aborts the running jobs and does not start the queued runs", func() {
+			s, job := newJob(schedules.OverlapQueue)
+			running := newRunningExecer()
+			job.running = running
+			job.state.Running = true
+			s.runDue(job)
+			So(job.state.Queued, ShouldBeTrue)
+
+			go func() {
+				for !running.isAbortRequested() {
+					time.Sleep(10 * time.Millisecond)
+				}
+				s.results <- scheduledRunResult{job: job, exitCode: -1}
+			}()
+			s.stop(os.Interrupt, 1)
+
+			So(running.isAbortRequested(), ShouldBeTrue)
+			So(job.running == nil, ShouldBeTrue)
+			So(job.state.Running, ShouldBeFalse)
+			So(job.state.Queued, ShouldBeFalse)
+			So(job.state.RunCount, ShouldEqual, 0)
+			So(job.state.NextRunTime.IsZero(), ShouldBeTrue)
+
+			stateJson, err := ioutil.ReadFile(s.stateFilePath)
+			So(err, ShouldBeNil)
+			So(string(stateJson), ShouldContainSubstring, `"Name": "sync"`)
+		})
+	})
+}
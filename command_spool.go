@@ -0,0 +1,343 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-zero-boilerplate/loggers"
+
+	"github.com/golang-devops/exec-logger/exec_logger_constants"
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/job_config"
+)
+
+const (
+	spoolJobFileExtension = ".json"
+	//spoolLeftoverAge is how long the alive file of a running job must be unchanged before the job is failed as a leftover of a stopped runner
+	spoolLeftoverAge = time.Minute
+)
+
+//getSpoolJobSchema only allows the timeouts of the flags in a spool job file, the other flags are those of the daemon
+func getSpoolJobSchema() *job_config.Schema {
+	flagSet := flag.NewFlagSet("spool-job", flag.ContinueOnError)
+	flagSet.Duration("timeout-kill", 0, "")
+	flagSet.Duration("job-timeout", 0, "")
+	return &job_config.Schema{FlagSet: flagSet}
+}
+
+//spoolJob is a claimed job file, its run directory is next to it with the same name (without extension)
+type spoolJob struct {
+	id          string
+	jobFilePath string
+	runDir      string
+	execer      *commandExecer
+}
+
+type spoolJobResult struct {
+	job      *spoolJob
+	exitCode int
+	err      error
+}
+
+//spoolRunner runs the job files that appear in the spool directory, at most `concurrency` at the same time
+type spoolRunner struct {
+	logger      loggers.LoggerStdIO
+	options     execOptions
+	spoolDir    string
+	concurrency int
+
+	running map[string]*spoolJob
+	results chan spoolJobResult
+}
+
+func (s *spoolRunner) subDir(name string) string {
+	return filepath.Join(s.spoolDir, name)
+}
+
+//getPendingJobFiles returns the job files in the spool directory, oldest first. Hidden files are ignored so
+//a job file can be written to a hidden temp file and then renamed
+func (s *spoolRunner) getPendingJobFiles() ([]string, error) {
+	infos, err := ioutil.ReadDir(s.spoolDir)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list spool dir '%s', error: %s", s.spoolDir, err.Error())
+	}
+	sort.Sort(fileInfosByModTime(infos))
+
+	names := []string{}
+	for _, info := range infos {
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") || filepath.Ext(info.Name()) != spoolJobFileExtension {
+			continue
+		}
+		names = append(names, info.Name())
+	}
+	return names, nil
+}
+
+//newSpoolJob returns the job with the time and name as id. A job file with the same name that was claimed in the same
+//second gets a number suffix, otherwise the claim (or the move to done or failed) would overwrite it
+func (s *spoolRunner) newSpoolJob(fileName string, now time.Time) *spoolJob {
+	runningDir := s.subDir(exec_logger_constants.SPOOL_RUNNING_DIR_NAME)
+	baseID := now.Format("20060102-150405") + "-" + strings.TrimSuffix(fileName, spoolJobFileExtension)
+	for i := 1; ; i++ {
+		id := baseID
+		if i > 1 {
+			id = fmt.Sprintf("%s-%d", baseID, i)
+		}
+		if !s.isJobIDUsed(id) {
+			return &spoolJob{
+				id:          id,
+				jobFilePath: filepath.Join(runningDir, id+spoolJobFileExtension),
+				runDir:      filepath.Join(runningDir, id),
+			}
+		}
+	}
+}
+
+func (s *spoolRunner) isJobIDUsed(id string) bool {
+	for _, name := range []string{exec_logger_constants.SPOOL_RUNNING_DIR_NAME, exec_logger_constants.SPOOL_DONE_DIR_NAME, exec_logger_constants.SPOOL_FAILED_DIR_NAME} {
+		for _, path := range []string{filepath.Join(s.subDir(name), id+spoolJobFileExtension), filepath.Join(s.subDir(name), id)} {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//claim moves the job file into the running directory. The rename is atomic, so when another runner claimed it first
+//(or it was removed) it returns nil without an error. The job file gets the claim time as modification time, it is the
+//alive time of the job until its alive file is written
+func (s *spoolRunner) claim(fileName string) (*spoolJob, error) {
+	now := time.Now()
+	job := s.newSpoolJob(fileName, now)
+
+	if err := os.Rename(filepath.Join(s.spoolDir, fileName), job.jobFilePath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Cannot claim job file '%s', error: %s", fileName, err.Error())
+	}
+	if err := os.Chtimes(job.jobFilePath, now, now); err != nil {
+		s.logger.Err("Cannot set the claim time of job file '%s', error: %s", job.jobFilePath, err.Error())
+	}
+	return job, nil
+}
+
+//newJobExecer creates the execer of the job file, the flags of the daemon apply to all jobs
+func (s *spoolRunner) newJobExecer(job *spoolJob) (*commandExecer, error) {
+	config, err := job_config.Load(job.jobFilePath, getSpoolJobSchema())
+	if err != nil {
+		return nil, err
+	}
+
+	options := s.options
+	options.runDir = job.runDir
+	options.env = append(append([]string{}, s.options.env...), config.EnvList()...)
+	if config.WorkingDir != "" {
+		options.workingDir = config.WorkingDir
+	}
+	options.steps = getJobSteps(config)
+	options.tasks = getJobTasks(config)
+	for _, option := range config.Options {
+		//The values were already validated against the schema
+		duration, _ := time.ParseDuration(option.Value)
+		switch option.Name {
+		case "timeout-kill":
+			options.timeoutKillDuration = duration
+		case "job-timeout":
+			options.jobTimeout = duration
+		}
+	}
+	if options.outputTriggers != nil {
+		options.outputTriggers = options.outputTriggers.Clone()
+	}
+
+	runArgs := config.RunArgs()
+	if len(runArgs) == 0 && len(options.steps) == 0 && len(options.tasks) == 0 {
+		return nil, fmt.Errorf("The job file '%s' has no command, shell, steps or tasks", job.jobFilePath)
+	}
+	return NewCommandExecer(s.logger, options, runArgs), nil
+}
+
+//moveFinished moves the job file and its run directory into the done or failed directory
+func (s *spoolRunner) moveFinished(job *spoolJob, succeeded bool) {
+	targetDir := s.subDir(exec_logger_constants.SPOOL_FAILED_DIR_NAME)
+	if succeeded {
+		targetDir = s.subDir(exec_logger_constants.SPOOL_DONE_DIR_NAME)
+	}
+
+	for _, path := range []string{job.runDir, job.jobFilePath} {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		targetPath := filepath.Join(targetDir, filepath.Base(path))
+		if err := os.Rename(path, targetPath); err != nil {
+			s.logger.Err("Cannot move '%s' to '%s', error: %s", path, targetPath, err.Error())
+		}
+	}
+}
+
+//failWithoutRunning writes the exited file of a job that could not run and moves it to the failed directory
+func (s *spoolRunner) failWithoutRunning(job *spoolJob, err error) {
+	s.logger.Err("Job '%s' failed, error: %s", job.id, err.Error())
+	statusHandler := &execStatusHandler{
+		exitedFilePath: filepath.Join(job.runDir, exec_logger_constants.EXITED_FILE_NAME),
+		redactor:       s.options.redactor,
+	}
+	if _, writeErr := statusHandler.WriteExitedJson(-1, err, 0, exec_logger_dtos.ExitOutcomeFailed, nil, nil, nil); writeErr != nil {
+		s.logger.Err("Cannot write exited file of job '%s', error: %s", job.id, writeErr.Error())
+	}
+	s.moveFinished(job, false)
+}
+
+func (s *spoolRunner) start(job *spoolJob) {
+	execer, err := s.newJobExecer(job)
+	if err != nil {
+		s.failWithoutRunning(job, err)
+		return
+	}
+
+	job.execer = execer
+	s.running[job.id] = job
+	s.logger.Out("Starting job '%s' in '%s'", job.id, job.runDir)
+	go func() {
+		exitCode, err := execer.Run()
+		s.results <- spoolJobResult{job: job, exitCode: exitCode, err: err}
+	}()
+}
+
+func (s *spoolRunner) finish(result spoolJobResult) {
+	delete(s.running, result.job.id)
+	outcome := result.job.execer.Outcome()
+	s.logger.Out("Job '%s' finished with exit code %d (%s)", result.job.id, result.exitCode, outcome)
+	s.moveFinished(result.job, outcome == exec_logger_dtos.ExitOutcomeSuccess)
+}
+
+//isLeftover returns true if the running job is not alive anymore, the runner that claimed it writes its alive file every few seconds
+func (s *spoolRunner) isLeftover(job *spoolJob, now time.Time) bool {
+	aliveTime := time.Time{}
+	for _, path := range []string{job.jobFilePath, filepath.Join(job.runDir, exec_logger_constants.ALIVE_FILE_NAME)} {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(aliveTime) {
+			aliveTime = info.ModTime()
+		}
+	}
+	return now.Sub(aliveTime) > spoolLeftoverAge
+}
+
+//failLeftovers moves the jobs that were still running when an earlier runner stopped unexpectedly to the failed directory.
+//The jobs of other runners that share the spool directory are still alive, so they are kept
+func (s *spoolRunner) failLeftovers() error {
+	runningDir := s.subDir(exec_logger_constants.SPOOL_RUNNING_DIR_NAME)
+	infos, err := ioutil.ReadDir(runningDir)
+	if err != nil {
+		return fmt.Errorf("Cannot list running dir '%s', error: %s", runningDir, err.Error())
+	}
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != spoolJobFileExtension {
+			continue
+		}
+		id := strings.TrimSuffix(info.Name(), spoolJobFileExtension)
+		job := &spoolJob{
+			id:          id,
+			jobFilePath: filepath.Join(runningDir, info.Name()),
+			runDir:      filepath.Join(runningDir, id),
+		}
+		if !s.isLeftover(job, time.Now()) {
+			continue
+		}
+		s.logger.Err("Job '%s' is not alive anymore, its spool runner stopped unexpectedly, moving it to failed", id)
+		s.moveFinished(job, false)
+	}
+	return nil
+}
+
+//run polls the spool directory for job files until a stop signal is received, then it aborts the running jobs
+func (s *spoolRunner) run(stopSignals chan os.Signal) error {
+	for _, name := range []string{exec_logger_constants.SPOOL_RUNNING_DIR_NAME, exec_logger_constants.SPOOL_DONE_DIR_NAME, exec_logger_constants.SPOOL_FAILED_DIR_NAME} {
+		if err := os.MkdirAll(s.subDir(name), 0755); err != nil {
+			return fmt.Errorf("Cannot create spool dir '%s', error: %s", s.subDir(name), err.Error())
+		}
+	}
+	if err := s.failLeftovers(); err != nil {
+		return err
+	}
+
+	s.running = make(map[string]*spoolJob)
+	s.results = make(chan spoolJobResult, s.concurrency)
+
+	for {
+		if len(s.running) < s.concurrency {
+			fileNames, err := s.getPendingJobFiles()
+			if err != nil {
+				s.logger.Err("%s", err.Error())
+			}
+			for _, fileName := range fileNames {
+				if len(s.running) >= s.concurrency {
+					break
+				}
+				job, err := s.claim(fileName)
+				if err != nil {
+					s.logger.Err("%s", err.Error())
+					continue
+				}
+				if job != nil {
+					s.start(job)
+				}
+			}
+		}
+
+		select {
+		case result := <-s.results:
+			s.finish(result)
+		case <-time.After(2 * time.Second):
+		case sig := <-stopSignals:
+			s.logger.Out("Got signal %s, aborting the %d running jobs", sig.String(), len(s.running))
+			for _, job := range s.running {
+				job.execer.RequestAbort()
+			}
+			for len(s.running) > 0 {
+				s.finish(<-s.results)
+			}
+			return nil
+		}
+	}
+}
+
+type fileInfosByModTime []os.FileInfo
+
+func (f fileInfosByModTime) Len() int      { return len(f) }
+func (f fileInfosByModTime) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+func (f fileInfosByModTime) Less(i, j int) bool {
+	if f[i].ModTime().Equal(f[j].ModTime()) {
+		return f[i].Name() < f[j].Name()
+	}
+	return f[i].ModTime().Before(f[j].ModTime())
+}
+
+//handleSpoolCommand runs the job files of the spool directory until the process gets an interrupt or terminate signal
+func handleSpoolCommand(logger loggers.LoggerStdIO, options execOptions, spoolDir string, concurrency int) error {
+	if concurrency < 1 {
+		return fmt.Errorf("The concurrency must be at least 1, got %d", concurrency)
+	}
+
+	stopSignals := make(chan os.Signal, 1)
+	signal.Notify(stopSignals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stopSignals)
+
+	logger.Out("Running the job files of spool dir '%s', at most %d at the same time", spoolDir, concurrency)
+	runner := &spoolRunner{
+		logger:      logger,
+		options:     options,
+		spoolDir:    spoolDir,
+		concurrency: concurrency,
+	}
+	return runner.run(stopSignals)
+}
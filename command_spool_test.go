@@ -0,0 +1,165 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/exec_logger_constants"
+)
+
+func TestSpoolRunner(t *testing.T) {
+	Convey("Testing spoolRunner", t, func() {
+		spoolDir, err := ioutil.TempDir("", "exec-logger-test-spool-")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(spoolDir)
+
+		runner := &spoolRunner{
+			logger:      NewStdioLogger(),
+			spoolDir:    spoolDir,
+			concurrency: 1,
+			running:     make(map[string]*spoolJob),
+			results:     make(chan spoolJobResult, 1),
+		}
+		for _, name := range []string{exec_logger_constants.SPOOL_RUNNING_DIR_NAME, exec_logger_constants.SPOOL_DONE_DIR_NAME, exec_logger_constants.SPOOL_FAILED_DIR_NAME} {
+			So(os.MkdirAll(runner.subDir(name), 0755), ShouldBeNil)
+		}
+		submit := func(fileName, content string) {
+			So(ioutil.WriteFile(filepath.Join(spoolDir, fileName), []byte(content), 0600), ShouldBeNil)
+		}
+		exists := func(path string) bool {
+			_, err := os.Stat(path)
+			return err == nil
+		}
+
+		Convey("Only the visible job files are pending", func() {
+			submit("a.json", `{"command": ["echo", "a"]}`)
+			submit(".b.json", `{"command": ["echo", "b"]}`)
+			submit("c.txt", `not a job`)
+
+			fileNames, err := runner.getPendingJobFiles()
+			So(err, ShouldBeNil)
+			So(fileNames, ShouldResemble, []string{"a.json"})
+		})
+
+		Convey("Claiming moves the job file into the running directory", func() {
+			submit("a.json", `{"command": ["echo", "a"]}`)
+			job, err := runner.claim("a.json")
+			So(err, ShouldBeNil)
+			So(job, ShouldNotBeNil)
+			So(filepath.Dir(job.jobFilePath), ShouldEqual, runner.subDir(exec_logger_constants.SPOOL_RUNNING_DIR_NAME))
+			So(exists(job.jobFilePath), ShouldBeTrue)
+			So(exists(filepath.Join(spoolDir, "a.json")), ShouldBeFalse)
+
+			Convey("A job file that is already claimed is skipped", func() {
+				job, err := runner.claim("a.json")
+				So(err, ShouldBeNil)
+				So(job, ShouldBeNil)
+			})
+
+			Convey("Claiming the same name in the same second does not overwrite the claimed job", func() {
+				submit("a.json", `{"command": ["echo", "a2"]}`)
+				now, err := time.ParseInLocation("20060102-150405", job.id[:len("20060102-150405")], time.Local)
+				So(err, ShouldBeNil)
+				So(runner.newSpoolJob("a.json", now).id, ShouldEqual, job.id+"-2")
+
+				second, err := runner.claim("a.json")
+				So(err, ShouldBeNil)
+				So(second.id, ShouldNotEqual, job.id)
+				So(exists(job.jobFilePath), ShouldBeTrue)
+				So(exists(second.jobFilePath), ShouldBeTrue)
+			})
+		})
+
+		Convey("An invalid job is moved to failed with the problems in its exited file", func() {
+			submit("invalid.json", `{"timeout-kill": "soon"}`)
+			job, err := runner.claim("invalid.json")
+			So(err, ShouldBeNil)
+			runner.start(job)
+
+			So(runner.running, ShouldBeEmpty)
+			failedDir := runner.subDir(exec_logger_constants.SPOOL_FAILED_DIR_NAME)
+			So(exists(filepath.Join(failedDir, job.id+spoolJobFileExtension)), ShouldBeTrue)
+			exitedJson, err := ioutil.ReadFile(filepath.Join(failedDir, job.id, exec_logger_constants.EXITED_FILE_NAME))
+			So(err, ShouldBeNil)
+			So(string(exitedJson), ShouldContainSubstring, "timeout-kill")
+		})
+
+		Convey("Finished jobs are moved to done or failed with their run directory", func() {
+			for _, succeeded := range []bool{true, false} {
+				submit("a.json", `{"command": ["echo", "a"]}`)
+				job, err := runner.claim("a.json")
+				So(err, ShouldBeNil)
+				So(os.MkdirAll(job.runDir, 0755), ShouldBeNil)
+
+				runner.moveFinished(job, succeeded)
+				targetDir := runner.subDir(exec_logger_constants.SPOOL_FAILED_DIR_NAME)
+				if succeeded {
+					targetDir = runner.subDir(exec_logger_constants.SPOOL_DONE_DIR_NAME)
+				}
+				So(exists(filepath.Join(targetDir, job.id+spoolJobFileExtension)), ShouldBeTrue)
+				So(exists(filepath.Join(targetDir, job.id)), ShouldBeTrue)
+				So(exists(job.jobFilePath), ShouldBeFalse)
+			}
+
+			//The job id of the moved jobs is still in use
+			now := time.Now()
+			So(runner.newSpoolJob("b.json", now).id, ShouldEqual, runner.newSpoolJob("b.json", now).id)
+			submit("b.json", `{"command": ["echo", "b"]}`)
+			job, err := runner.claim("b.json")
+			So(err, ShouldBeNil)
+			runner.moveFinished(job, true)
+			So(runner.newSpoolJob("b.json", now).id, ShouldNotEqual, job.id)
+		})
+
+		Convey("Only the jobs that are not alive anymore are failed as leftovers", func() {
+			submit("alive.json", `{"command": ["echo", "a"]}`)
+			submit("stopped.json", `{"command": ["echo", "b"]}`)
+			alive, err := runner.claim("alive.json")
+			So(err, ShouldBeNil)
+			stopped, err := runner.claim("stopped.json")
+			So(err, ShouldBeNil)
+			longAgo := time.Now().Add(-2 * spoolLeftoverAge)
+			So(os.Chtimes(stopped.jobFilePath, longAgo, longAgo), ShouldBeNil)
+
+			So(runner.isLeftover(alive, time.Now()), ShouldBeFalse)
+			So(runner.isLeftover(alive, time.Now().Add(2*spoolLeftoverAge)), ShouldBeTrue)
+			So(runner.isLeftover(stopped, time.Now()), ShouldBeTrue)
+
+			Convey("A job that writes its alive file is alive", func() {
+				aliveFilePath := filepath.Join(stopped.runDir, exec_logger_constants.ALIVE_FILE_NAME)
+				So(os.MkdirAll(filepath.Dir(aliveFilePath), 0755), ShouldBeNil)
+				So(ioutil.WriteFile(aliveFilePath, []byte("now"), 0600), ShouldBeNil)
+				So(runner.isLeftover(stopped, time.Now()), ShouldBeFalse)
+			})
+
+			Convey("The leftovers are moved to failed", func() {
+				So(runner.failLeftovers(), ShouldBeNil)
+				So(exists(alive.jobFilePath), ShouldBeTrue)
+				So(exists(filepath.Join(runner.subDir(exec_logger_constants.SPOOL_FAILED_DIR_NAME), stopped.id+spoolJobFileExtension)), ShouldBeTrue)
+			})
+		})
+
+		if runtime.GOOS != "windows" {
+			Convey("A job is run and moved to done", func() {
+				submit("echo.json", `{"command": ["echo", "hello"]}`)
+				job, err := runner.claim("echo.json")
+				So(err, ShouldBeNil)
+				runner.start(job)
+				So(runner.running, ShouldContainKey, job.id)
+
+				runner.finish(<-runner.results)
+				So(runner.running, ShouldBeEmpty)
+				doneDir := runner.subDir(exec_logger_constants.SPOOL_DONE_DIR_NAME)
+				logged, err := ioutil.ReadFile(filepath.Join(doneDir, job.id, exec_logger_constants.LOG_FILE_NAME))
+				So(err, ShouldBeNil)
+				So(string(logged), ShouldContainSubstring, "] hello")
+			})
+		}
+	})
+}
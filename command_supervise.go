@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-zero-boilerplate/loggers"
+
+	"github.com/golang-devops/exec-logger/exec_logger_constants"
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/log_segments"
+	"github.com/golang-devops/exec-logger/sleep_durations"
+)
+
+//restartPolicy determines after which exits the supervise task restarts the command
+type restartPolicy string
+
+const (
+	restartAlways    restartPolicy = "always"
+	restartOnFailure restartPolicy = "on-failure"
+)
+
+var allRestartPolicies = []restartPolicy{restartAlways, restartOnFailure}
+
+func getRestartPolicyNamesForFlagHelp() (names []string) {
+	for _, p := range allRestartPolicies {
+		names = append(names, string(p))
+	}
+	return
+}
+
+func parseRestartPolicy(s string) (restartPolicy, error) {
+	for _, p := range allRestartPolicies {
+		if strings.EqualFold(string(p), strings.TrimSpace(s)) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("Unsupported restart policy '%s', expected one of: %s", s, strings.Join(getRestartPolicyNamesForFlagHelp(), ", "))
+}
+
+//parseDurationList parses comma separated durations like 1s,10s,1m
+func parseDurationList(s string) (durations []time.Duration, err error) {
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("Invalid duration '%s', expected for example 1s or 5m", part)
+		}
+		durations = append(durations, d)
+	}
+	if len(durations) == 0 {
+		return nil, fmt.Errorf("At least one duration is required")
+	}
+	return durations, nil
+}
+
+//supervisor runs the command again after it exited, according to the restart policy
+type supervisor struct {
+	logger        loggers.LoggerStdIO
+	options       execOptions
+	runArgs       []string
+	policy        restartPolicy
+	maxRestarts   int
+	restartWindow time.Duration
+	backoff       []time.Duration
+
+	backoffIncreaser sleep_durations.DurationIncreaser
+	restartTimes     []time.Time
+}
+
+//decide returns whether to restart the command after it ran for `ranFor` with the `outcome`, and how long to wait first.
+//The backoff starts over once an incarnation ran for longer than the restart window
+func (s *supervisor) decide(outcome string, mustAbort bool, ranFor time.Duration, now time.Time) (decision string, delay time.Duration) {
+	if mustAbort || (s.policy == restartOnFailure && outcome == exec_logger_dtos.ExitOutcomeSuccess) {
+		return exec_logger_dtos.SupervisorDecisionStop, 0
+	}
+
+	recentRestarts := []time.Time{}
+	for _, t := range s.restartTimes {
+		if now.Sub(t) < s.restartWindow {
+			recentRestarts = append(recentRestarts, t)
+		}
+	}
+	s.restartTimes = recentRestarts
+	if s.maxRestarts > 0 && len(s.restartTimes) >= s.maxRestarts {
+		return exec_logger_dtos.SupervisorDecisionGiveUp, 0
+	}
+
+	if s.backoffIncreaser == nil || ranFor >= s.restartWindow {
+		s.backoffIncreaser = sleep_durations.New(1, s.backoff)
+	}
+	s.restartTimes = append(s.restartTimes, now)
+	return exec_logger_dtos.SupervisorDecisionRestart, s.backoffIncreaser.Next()
+}
+
+//sleepWithHeartbeat keeps the alive file updated while waiting to restart, it returns false if the must-abort file was found
+func (s *supervisor) sleepWithHeartbeat(statusHandler *execStatusHandler, delay time.Duration) bool {
+	deadline := time.Now().Add(delay)
+	for {
+		if err := statusHandler.WriteAlive(); err != nil {
+			s.logger.Err("Cannot write alive file, error: %s", err.Error())
+		}
+		if mustAbort, err := statusHandler.CheckMustAbort(); err != nil {
+			s.logger.Err("Unable to check for abort request, error: %s", err.Error())
+		} else if mustAbort {
+			return false
+		}
+
+		remaining := deadline.Sub(time.Now())
+		if remaining <= 0 {
+			return true
+		}
+		if remaining > 2*time.Second {
+			remaining = 2 * time.Second
+		}
+		time.Sleep(remaining)
+	}
+}
+
+//run runs the command until the restart policy stops it, it returns the exit code and error of the last incarnation.
+//All incarnations append to the same log file
+func (s *supervisor) run() (exitCode int, returnErr error) {
+	options := s.options
+	options.keepPreviousLog = true
+
+	logFilePath := filepath.Join(options.runDir, exec_logger_constants.LOG_FILE_NAME)
+	if err := log_segments.RemoveAll(logFilePath); err != nil {
+		return -1, fmt.Errorf("Failure to remove log file, error: %s", err.Error())
+	}
+	historyFilePath := filepath.Join(options.runDir, exec_logger_constants.HISTORY_FILE_NAME)
+	if err := os.Remove(historyFilePath); err != nil && !os.IsNotExist(err) {
+		return -1, fmt.Errorf("Cannot remove history file '%s', error: %s", historyFilePath, err.Error())
+	}
+
+	for incarnation := 1; ; incarnation++ {
+		options.incarnation = incarnation
+		if s.options.outputTriggers != nil {
+			options.outputTriggers = s.options.outputTriggers.Clone()
+		}
+
+		execer := NewCommandExecer(s.logger, options, s.runArgs)
+		startTime := time.Now()
+		exitCode, returnErr = execer.Run()
+		exitTime := time.Now()
+
+		mustAbort, err := execer.statusHandler.CheckMustAbort()
+		if err != nil {
+			s.logger.Err("Unable to check for abort request, error: %s", err.Error())
+		}
+
+		dto := &exec_logger_dtos.IncarnationDto{
+			Incarnation: incarnation,
+			StartTime:   startTime.UTC(),
+			ExitTime:    exitTime.UTC(),
+			Duration:    exitTime.Sub(startTime).String(),
+			ExitCode:    exitCode,
+			Outcome:     execer.Outcome(),
+		}
+		if returnErr != nil {
+			dto.Error = options.redactor.Redact(returnErr.Error())
+		}
+
+		var delay time.Duration
+		dto.Decision, delay = s.decide(dto.Outcome, mustAbort, exitTime.Sub(startTime), exitTime)
+		if dto.Decision == exec_logger_dtos.SupervisorDecisionRestart {
+			dto.RestartDelay = delay.String()
+		}
+		if err := execer.statusHandler.AppendHistory(dto); err != nil {
+			s.logger.Err("%s", err.Error())
+		}
+
+		switch dto.Decision {
+		case exec_logger_dtos.SupervisorDecisionGiveUp:
+			s.logger.Err("Incarnation %d exited with code %d (%s), giving up after %d restarts within %s", incarnation, exitCode, dto.Outcome, s.maxRestarts, s.restartWindow.String())
+			if returnErr == nil {
+				returnErr = fmt.Errorf("Gave up after %d restarts within %s", s.maxRestarts, s.restartWindow.String())
+			}
+			return exitCode, returnErr
+		case exec_logger_dtos.SupervisorDecisionStop:
+			s.logger.Out("Incarnation %d exited with code %d (%s), not restarting", incarnation, exitCode, dto.Outcome)
+			return exitCode, returnErr
+		}
+
+		s.logger.Out("Incarnation %d exited with code %d (%s), restarting in %s", incarnation, exitCode, dto.Outcome, delay.String())
+		if !s.sleepWithHeartbeat(execer.statusHandler, delay) {
+			s.logger.Out("Got ABORT message while waiting to restart, not restarting")
+			return exitCode, returnErr
+		}
+	}
+}
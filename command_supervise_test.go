@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+)
+
+func TestSupervisorDecide(t *testing.T) {
+	Convey("Testing supervisor restart decisions", t, func() {
+		t0 := time.Date(2016, 5, 7, 10, 0, 0, 0, time.UTC)
+		backoff, err := parseDurationList("1s, 5s,30s")
+		So(err, ShouldBeNil)
+
+		s := &supervisor{policy: restartOnFailure, maxRestarts: 3, restartWindow: time.Minute, backoff: backoff}
+
+		decision, _ := s.decide(exec_logger_dtos.ExitOutcomeSuccess, false, time.Second, t0)
+		So(decision, ShouldEqual, exec_logger_dtos.SupervisorDecisionStop)
+		decision, _ = s.decide(exec_logger_dtos.ExitOutcomeFailed, true, time.Second, t0)
+		So(decision, ShouldEqual, exec_logger_dtos.SupervisorDecisionStop)
+
+		delays := []time.Duration{}
+		for i := 0; i < 3; i++ {
+			decision, delay := s.decide(exec_logger_dtos.ExitOutcomeFailed, false, time.Second, t0.Add(time.Duration(i)*10*time.Second))
+			So(decision, ShouldEqual, exec_logger_dtos.SupervisorDecisionRestart)
+			delays = append(delays, delay)
+		}
+		So(delays, ShouldResemble, []time.Duration{time.Second, 5 * time.Second, 30 * time.Second})
+
+		decision, _ = s.decide(exec_logger_dtos.ExitOutcomeFailed, false, time.Second, t0.Add(30*time.Second))
+		So(decision, ShouldEqual, exec_logger_dtos.SupervisorDecisionGiveUp)
+
+		//The first restart is out of the window and the command ran long enough to start the backoff over
+		decision, delay := s.decide(exec_logger_dtos.ExitOutcomeTimedOut, false, 2*time.Minute, t0.Add(61*time.Second))
+		So(decision, ShouldEqual, exec_logger_dtos.SupervisorDecisionRestart)
+		So(delay, ShouldEqual, time.Second)
+
+		s.policy = restartAlways
+		decision, _ = s.decide(exec_logger_dtos.ExitOutcomeSuccess, false, 2*time.Minute, t0.Add(5*time.Minute))
+		So(decision, ShouldEqual, exec_logger_dtos.SupervisorDecisionRestart)
+
+		_, err = parseDurationList("1s,soon")
+		So(err, ShouldNotBeNil)
+	})
+}
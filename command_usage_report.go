@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/golang-devops/exec-logger/exec_logger_constants"
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/usage_report"
+)
+
+func getUsageReportFormatNamesForFlagHelp() (names []string) {
+	for _, f := range usage_report.Formats {
+		names = append(names, f.Name)
+	}
+	return
+}
+
+func handleUsageReportCommand(format, outputFilePath string) error {
+	var writeReport func(io.Writer, *usage_report.Report) error
+	for _, f := range usage_report.Formats {
+		if strings.EqualFold(f.Name, format) {
+			writeReport = f.Writer
+		}
+	}
+	if writeReport == nil {
+		return fmt.Errorf("Unsupported report format '%s', expected one of: %s", format, strings.Join(getUsageReportFormatNamesForFlagHelp(), ", "))
+	}
+
+	usageFile, err := os.Open(exec_logger_constants.RECORD_RESOURCE_USAGE_FILE_NAME)
+	if err != nil {
+		return err
+	}
+	defer usageFile.Close()
+
+	samples, err := exec_logger_dtos.ReadAllResourceUsage(usageFile)
+	if err != nil {
+		return fmt.Errorf("Cannot read resource usage file '%s', error: %s", exec_logger_constants.RECORD_RESOURCE_USAGE_FILE_NAME, err.Error())
+	}
+
+	report := usage_report.Build(samples)
+
+	if outputFilePath == "" {
+		return writeReport(os.Stdout, report)
+	}
+
+	outputFile, err := os.Create(outputFilePath)
+	if err != nil {
+		return fmt.Errorf("Cannot create report file '%s', error: %s", outputFilePath, err.Error())
+	}
+	defer outputFile.Close()
+
+	if err = writeReport(outputFile, report); err != nil {
+		return fmt.Errorf("Cannot write report file '%s', error: %s", outputFilePath, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package diagnostics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-zero-boilerplate/osvisitors"
+	"github.com/shirou/gopsutil/process"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/process_tree"
+)
+
+//Capture takes a diagnostic snapshot of the process tree of `mainProcID`. Anything that could not be read is recorded in the Warnings or per process Errors
+func Capture(mainProcID int, reason string) *exec_logger_dtos.DiagnosticsDto {
+	dto := &exec_logger_dtos.DiagnosticsDto{
+		Time:   time.Now(),
+		Reason: reason,
+	}
+
+	tree, err := process_tree.LoadProcessTree(mainProcID)
+	if err != nil {
+		dto.Warnings = append(dto.Warnings, fmt.Sprintf("Cannot load process tree of pid %d, error: %s", mainProcID, err.Error()))
+		return dto
+	}
+
+	runtimeOsType, err := osvisitors.GetRuntimeOsType()
+	if err != nil {
+		dto.Warnings = append(dto.Warnings, fmt.Sprintf("Cannot determine runtime os type, error: %s", err.Error()))
+	}
+
+	tree.Walk(func(p *process_tree.Process, parentPid int) {
+		procDiagnostics := &exec_logger_dtos.ProcessDiagnosticsDto{
+			Pid:        p.Pid,
+			ParentPid:  parentPid,
+			Name:       p.Name,
+			Exe:        p.Exe,
+			Cmdline:    p.Cmdline,
+			NumThreads: p.NumThreads,
+		}
+		fillProcessDetails(procDiagnostics)
+
+		if runtimeOsType != nil {
+			runtimeOsType.Accept(&visitorKernelDetails{dto: procDiagnostics})
+		}
+
+		dto.Processes = append(dto.Processes, procDiagnostics)
+	})
+
+	return dto
+}
+
+func fillProcessDetails(dto *exec_logger_dtos.ProcessDiagnosticsDto) {
+	proc, err := process.NewProcess(int32(dto.Pid))
+	if err != nil {
+		dto.Errors = append(dto.Errors, fmt.Sprintf("Cannot load process, error: %s", err.Error()))
+		return
+	}
+
+	if status, err := proc.Status(); err != nil {
+		dto.Errors = append(dto.Errors, fmt.Sprintf("Cannot get status, error: %s", err.Error()))
+	} else {
+		dto.Status = status
+	}
+
+	if cwd, err := proc.Cwd(); err != nil {
+		dto.Errors = append(dto.Errors, fmt.Sprintf("Cannot get cwd, error: %s", err.Error()))
+	} else {
+		dto.Cwd = cwd
+	}
+
+	if openFiles, err := proc.OpenFiles(); err != nil {
+		dto.Errors = append(dto.Errors, fmt.Sprintf("Cannot get open files, error: %s", err.Error()))
+	} else {
+		for _, f := range openFiles {
+			dto.OpenFiles = append(dto.OpenFiles, f.Path)
+		}
+	}
+}
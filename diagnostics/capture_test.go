@@ -0,0 +1,75 @@
+package diagnostics
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/process_tree"
+)
+
+func TestDiagnostics(t *testing.T) {
+	Convey("Testing processMatchesAnyName", t, func() {
+		p := &process_tree.Process{Name: "java", Exe: filepath.Join("opt", "jdk", "bin", "java-wrapper")}
+		So(processMatchesAnyName(p, []string{"go", "JAVA"}), ShouldBeTrue)
+		So(processMatchesAnyName(p, []string{"java-wrapper"}), ShouldBeTrue)
+		So(processMatchesAnyName(p, []string{"jav", "wrapper"}), ShouldBeFalse)
+		So(processMatchesAnyName(p, nil), ShouldBeFalse)
+	})
+
+	Convey("Testing Capture of the current process", t, func() {
+		if runtime.GOOS != "windows" {
+			child := exec.Command("sleep", "5")
+			So(child.Start(), ShouldBeNil)
+			defer func() {
+				child.Process.Kill()
+				child.Wait()
+			}()
+		}
+
+		dto := Capture(os.Getpid(), "testing")
+		So(dto.Reason, ShouldEqual, "testing")
+		So(dto.Warnings, ShouldBeEmpty)
+		So(len(dto.Processes), ShouldBeGreaterThan, 0)
+
+		main := dto.Processes[0]
+		So(main.Pid, ShouldEqual, os.Getpid())
+		So(main.ParentPid, ShouldEqual, 0)
+		So(main.Name, ShouldNotBeEmpty)
+		So(main.NumThreads, ShouldBeGreaterThan, 0)
+
+		wd, err := os.Getwd()
+		So(err, ShouldBeNil)
+		So(main.Cwd, ShouldEqual, wd)
+
+		if runtime.GOOS != "windows" {
+			So(len(dto.Processes), ShouldEqual, 2)
+			So(dto.Processes[1].Name, ShouldEqual, "sleep")
+			So(dto.Processes[1].ParentPid, ShouldEqual, os.Getpid())
+			So(dto.Processes[1].Cmdline, ShouldEqual, "sleep 5")
+		}
+	})
+	Convey("Testing SendSignal to the processes of the tree with a name", t, func() {
+		if runtime.GOOS == "windows" {
+			return
+		}
+		shell := exec.Command("sh", "-c", "sleep 30; echo done")
+		So(shell.Start(), ShouldBeNil)
+		defer shell.Process.Kill()
+		time.Sleep(200 * time.Millisecond)
+
+		signaled, warnings := SendSignal(shell.Process.Pid, syscall.SIGTERM, "SIGTERM", []string{"sleep"})
+		So(warnings, ShouldBeEmpty)
+		So(len(signaled), ShouldEqual, 1)
+		So(signaled[0].Name, ShouldEqual, "sleep")
+
+		//The shell was not signaled, it exits normally once its sleep child was terminated
+		So(shell.Wait(), ShouldBeNil)
+	})
+}
@@ -0,0 +1,41 @@
+package diagnostics
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+)
+
+//visitorKernelDetails fills in what the process is waiting on in the kernel, this is only available on linux
+type visitorKernelDetails struct {
+	dto *exec_logger_dtos.ProcessDiagnosticsDto
+}
+
+func (v *visitorKernelDetails) readProcFile(name string) (string, error) {
+	content, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/%s", v.dto.Pid, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+func (v *visitorKernelDetails) VisitWindows() {}
+
+func (v *visitorKernelDetails) VisitLinux() {
+	if wchan, err := v.readProcFile("wchan"); err != nil {
+		v.dto.Errors = append(v.dto.Errors, fmt.Sprintf("Cannot read wchan, error: %s", err.Error()))
+	} else {
+		v.dto.Wchan = wchan
+	}
+
+	//The kernel stack is usually only readable by root
+	if stack, err := v.readProcFile("stack"); err != nil {
+		v.dto.Errors = append(v.dto.Errors, fmt.Sprintf("Cannot read kernel stack, error: %s", err.Error()))
+	} else {
+		v.dto.KernelStack = stack
+	}
+}
+
+func (v *visitorKernelDetails) VisitDarwin() {}
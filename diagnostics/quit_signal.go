@@ -0,0 +1,59 @@
+package diagnostics
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/shirou/gopsutil/process"
+
+	"github.com/golang-devops/exec-logger/process_tree"
+)
+
+func processMatchesAnyName(p *process_tree.Process, names []string) bool {
+	for _, name := range names {
+		if strings.EqualFold(p.Name, name) || strings.EqualFold(filepath.Base(p.Exe), name) {
+			return true
+		}
+	}
+	return false
+}
+
+//SendQuitSignal sends SIGQUIT to all processes in the tree of `mainProcID` with one of the names. Go and Java processes print their thread dumps on SIGQUIT
+func SendQuitSignal(mainProcID int, names []string) (signaled []*process_tree.Process, warnings []string) {
+	return SendSignal(mainProcID, syscall.SIGQUIT, "SIGQUIT", names)
+}
+
+//SendSignal sends the signal to all processes in the tree of `mainProcID` with one of the names, or to all of them if there
+//are no names. SIGKILL kills the processes, which also works on windows where the other signals can not be sent
+func SendSignal(mainProcID int, sig syscall.Signal, sigName string, names []string) (signaled []*process_tree.Process, warnings []string) {
+	tree, err := process_tree.LoadProcessTree(mainProcID)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("Cannot load process tree of pid %d, error: %s", mainProcID, err.Error())}
+	}
+
+	tree.Walk(func(p *process_tree.Process, parentPid int) {
+		if len(names) > 0 && !processMatchesAnyName(p, names) {
+			return
+		}
+
+		proc, err := process.NewProcess(int32(p.Pid))
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Cannot load process with pid %d, error: %s", p.Pid, err.Error()))
+			return
+		}
+		if sig == syscall.SIGKILL {
+			err = proc.Kill()
+		} else {
+			err = proc.SendSignal(sig)
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Cannot send %s to pid %d (%s), error: %s", sigName, p.Pid, p.Name, err.Error()))
+			return
+		}
+		signaled = append(signaled, p)
+	})
+
+	return
+}
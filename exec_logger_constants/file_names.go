@@ -13,4 +13,16 @@ var (
 	EXITED_FILE_NAME                = filepath.Join(__EXEC_LOGGER_FILES_SUBDIR, "exited.json")
 	MUST_ABORT_FILE_NAME            = filepath.Join(__EXEC_LOGGER_FILES_SUBDIR, "must-abort.txt")
 	RECORD_RESOURCE_USAGE_FILE_NAME = filepath.Join(__EXEC_LOGGER_FILES_SUBDIR, "resource-usage.json")
+	PROCESSES_FILE_NAME             = filepath.Join(__EXEC_LOGGER_FILES_SUBDIR, "processes.jsonl")
+	DIAGNOSTICS_FILE_NAME           = filepath.Join(__EXEC_LOGGER_FILES_SUBDIR, "diagnostics.json")
+	SNAPSHOTS_FILE_NAME             = filepath.Join(__EXEC_LOGGER_FILES_SUBDIR, "snapshots.jsonl")
+	HISTORY_FILE_NAME               = filepath.Join(__EXEC_LOGGER_FILES_SUBDIR, "history.jsonl")
+	TASKS_DIR_NAME                  = filepath.Join(__EXEC_LOGGER_FILES_SUBDIR, "tasks")
+
+	BATCH_EXITED_FILE_NAME   = "batch-exited.json"
+	SCHEDULE_STATE_FILE_NAME = "schedule-state.json"
+
+	SPOOL_RUNNING_DIR_NAME = "running"
+	SPOOL_DONE_DIR_NAME    = "done"
+	SPOOL_FAILED_DIR_NAME  = "failed"
 )
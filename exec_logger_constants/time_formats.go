@@ -1,5 +1,6 @@
 package exec_logger_constants
 
 const (
-	ALIVE_TIME_FORMAT = "2006-01-02 15:04:05"
+	ALIVE_TIME_FORMAT    = "2006-01-02 15:04:05"
+	LOG_LINE_TIME_FORMAT = "2006-01-02 15:04:05"
 )
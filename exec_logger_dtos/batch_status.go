@@ -0,0 +1,43 @@
+package exec_logger_dtos
+
+import "time"
+
+//BatchExitedDto is the summary of all the commands of a batch, written when the batch finished
+type BatchExitedDto struct {
+	StartTime   time.Time
+	ExitTime    time.Time
+	Duration    string
+	Concurrency int
+	FailFast    bool
+	Total       int
+	Succeeded   int
+	Failed      int
+	Skipped     int
+	Commands    []*BatchCommandStatusDto
+}
+
+//BatchCommandStatusDto is the status of a single command of a batch, its own files are in the RunDir
+type BatchCommandStatusDto struct {
+	Index       int
+	CommandLine string
+	RunDir      string
+	ExitCode    int
+	Error       string `json:",omitempty"`
+	Outcome     string
+	Duration    string `json:",omitempty"`
+}
+
+//Count fills the Total, Succeeded, Failed and Skipped counts from the Commands
+func (b *BatchExitedDto) Count() {
+	b.Total, b.Succeeded, b.Failed, b.Skipped = len(b.Commands), 0, 0, 0
+	for _, c := range b.Commands {
+		switch c.Outcome {
+		case ExitOutcomeSuccess:
+			b.Succeeded++
+		case ExitOutcomeSkipped:
+			b.Skipped++
+		default:
+			b.Failed++
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package exec_logger_dtos
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBatchExitedCount(t *testing.T) {
+	Convey("Testing BatchExitedDto Count", t, func() {
+		batch := &BatchExitedDto{
+			Commands: []*BatchCommandStatusDto{
+				{Index: 1, Outcome: ExitOutcomeSuccess},
+				{Index: 2, Outcome: ExitOutcomeAborted},
+				{Index: 3, Outcome: ExitOutcomeFailed},
+				{Index: 4, Outcome: ExitOutcomeSkipped},
+				{Index: 5, Outcome: ExitOutcomeSuccess},
+			},
+		}
+		batch.Count()
+		So(batch.Total, ShouldEqual, 5)
+		So(batch.Succeeded, ShouldEqual, 2)
+		So(batch.Failed, ShouldEqual, 2)
+		So(batch.Skipped, ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,27 @@
+package exec_logger_dtos
+
+import "time"
+
+//DiagnosticsDto is a snapshot of the process tree captured right before it is killed
+type DiagnosticsDto struct {
+	Time      time.Time
+	Reason    string
+	Processes []*ProcessDiagnosticsDto
+	Warnings  []string `json:",omitempty"`
+}
+
+//ProcessDiagnosticsDto holds the diagnostic details of a single process. Details that could not be read are listed in Errors
+type ProcessDiagnosticsDto struct {
+	Pid         int
+	ParentPid   int
+	Name        string
+	Exe         string
+	Cmdline     string
+	Cwd         string
+	Status      string
+	NumThreads  int
+	Wchan       string   `json:",omitempty"`
+	KernelStack string   `json:",omitempty"`
+	OpenFiles   []string `json:",omitempty"`
+	Errors      []string `json:",omitempty"`
+}
@@ -6,13 +6,47 @@ import (
 	"time"
 )
 
+const (
+	//ExitOutcomeSuccess means the command ran and exited successfully
+	ExitOutcomeSuccess = "success"
+	//ExitOutcomeFailed means the command could not run or exited with an error
+	ExitOutcomeFailed = "failed"
+	//ExitOutcomeTimedOut means the command was killed after the `-timeout-kill` duration
+	ExitOutcomeTimedOut = "timed-out"
+	//ExitOutcomeAborted means the command was killed due to the must-abort file
+	ExitOutcomeAborted = "aborted"
+	//ExitOutcomeResourceLimitExceeded means the command was killed because its process tree exceeded one of the resource limits
+	ExitOutcomeResourceLimitExceeded = "resource-limit-exceeded"
+	//ExitOutcomeSkipped means the step of a multi-step job (or command of a batch) did not run because an earlier one failed,
+	//or the task did not run because a task it depends on failed
+	ExitOutcomeSkipped = "skipped"
+)
+
 type ExitStatusDto struct {
 	ExitCode int
 	Error    string
 	ExitTime time.Time
 	Duration string
+	Outcome  string
+
+	ResourceLimitExceeded *ResourceLimitExceededDto `json:",omitempty"`
+	ResourceSummary       *ResourceSummaryDto       `json:",omitempty"`
+	Steps                 []*StepStatusDto          `json:",omitempty"`
+	Tasks                 []*TaskStatusDto          `json:",omitempty"`
+	CriticalPath          *CriticalPathDto          `json:",omitempty"`
 }
 
 func (e *ExitStatusDto) HasError() bool {
 	return strings.TrimSpace(e.Error) != ""
 }
+
+//ResourceLimitExceededDto describes which resource limit was exceeded
+type ResourceLimitExceededDto struct {
+	Limit    string
+	MaxValue string
+	Observed string
+}
+
+func (r *ResourceLimitExceededDto) String() string {
+	return r.Limit + " of " + r.MaxValue + " exceeded (observed " + r.Observed + ")"
+}
@@ -0,0 +1,25 @@
+package exec_logger_dtos
+
+import "time"
+
+const (
+	//SupervisorDecisionRestart means the supervisor restarts the command after the RestartDelay
+	SupervisorDecisionRestart = "restart"
+	//SupervisorDecisionStop means the command must not be restarted, due to the restart policy or the must-abort file
+	SupervisorDecisionStop = "stop"
+	//SupervisorDecisionGiveUp means the command was restarted too often within the restart window
+	SupervisorDecisionGiveUp = "give-up"
+)
+
+//IncarnationDto is the exit of a single run of a supervised command, appended to the history file
+type IncarnationDto struct {
+	Incarnation  int
+	StartTime    time.Time
+	ExitTime     time.Time
+	Duration     string
+	ExitCode     int
+	Error        string `json:",omitempty"`
+	Outcome      string
+	Decision     string
+	RestartDelay string `json:",omitempty"`
+}
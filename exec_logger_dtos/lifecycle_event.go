@@ -0,0 +1,13 @@
+package exec_logger_dtos
+
+import "time"
+
+//LifecycleEventDto is the json payload sent to the webhooks. The ExitStatus is only set for the finished event
+type LifecycleEventDto struct {
+	Event        string
+	Time         time.Time
+	CommandLine  string `json:",omitempty"`
+	Message      string `json:",omitempty"`
+	LocalContext *LocalContextDto
+	ExitStatus   *ExitStatusDto `json:",omitempty"`
+}
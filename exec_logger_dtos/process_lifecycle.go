@@ -0,0 +1,119 @@
+package exec_logger_dtos
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang-devops/exec-logger/process_tree"
+)
+
+const (
+	//ProcessLifecycleEventSpawn is when a process was seen for the first time
+	ProcessLifecycleEventSpawn = "spawn"
+	//ProcessLifecycleEventExit is when a process was no longer seen
+	ProcessLifecycleEventExit = "exit"
+)
+
+//ProcessLifecycleEventDto is a single line of the processes file. The exit time is only known to be between the LastSeen and the event Time
+type ProcessLifecycleEventDto struct {
+	Event     string
+	Time      time.Time
+	Pid       int
+	ParentPid int
+	StartTime int64
+	Name      string
+	Exe       string
+	Cmdline   string
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+//NewProcessLifecycleTracker creates a new ProcessLifecycleTracker
+func NewProcessLifecycleTracker() *ProcessLifecycleTracker {
+	return &ProcessLifecycleTracker{
+		alive: make(map[processKey]*ProcessLifecycleEventDto),
+	}
+}
+
+//ProcessLifecycleTracker diffs the process trees of consecutive samples into spawn and exit events. It is safe for concurrent use
+type ProcessLifecycleTracker struct {
+	sync.Mutex
+
+	alive    map[processKey]*ProcessLifecycleEventDto
+	order    []processKey
+	finished bool
+}
+
+//Track returns the events since the previous sample. Samples without a ProcessTree (for instance when it failed to load) are ignored
+func (t *ProcessLifecycleTracker) Track(dto *ResourceUsageDto) (events []*ProcessLifecycleEventDto) {
+	if dto.ProcessTree == nil || dto.ProcessTree.MainProcess == nil {
+		return nil
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	if t.finished {
+		return nil
+	}
+
+	current := make(map[processKey]bool)
+	dto.ProcessTree.Walk(func(p *process_tree.Process, parentPid int) {
+		key := processKey{pid: p.Pid, startTime: p.CreateTime}
+		current[key] = true
+
+		if tracked, ok := t.alive[key]; ok {
+			tracked.LastSeen = dto.Time
+			return
+		}
+
+		tracked := &ProcessLifecycleEventDto{
+			Pid:       p.Pid,
+			ParentPid: parentPid,
+			StartTime: p.CreateTime,
+			Name:      p.Name,
+			Exe:       p.Exe,
+			Cmdline:   p.Cmdline,
+			FirstSeen: dto.Time,
+			LastSeen:  dto.Time,
+		}
+		t.alive[key] = tracked
+		t.order = append(t.order, key)
+		events = append(events, t.newEvent(ProcessLifecycleEventSpawn, dto.Time, tracked))
+	})
+
+	remainingOrder := []processKey{}
+	for _, key := range t.order {
+		if current[key] {
+			remainingOrder = append(remainingOrder, key)
+			continue
+		}
+		events = append(events, t.newEvent(ProcessLifecycleEventExit, dto.Time, t.alive[key]))
+		delete(t.alive, key)
+	}
+	t.order = remainingOrder
+
+	return events
+}
+
+//Finish returns the exit events of all the processes that are still tracked, after this Track will no longer return events
+func (t *ProcessLifecycleTracker) Finish(exitTime time.Time) (events []*ProcessLifecycleEventDto) {
+	t.Lock()
+	defer t.Unlock()
+
+	for _, key := range t.order {
+		events = append(events, t.newEvent(ProcessLifecycleEventExit, exitTime, t.alive[key]))
+	}
+	t.alive = make(map[processKey]*ProcessLifecycleEventDto)
+	t.order = nil
+	t.finished = true
+
+	return events
+}
+
+func (t *ProcessLifecycleTracker) newEvent(event string, eventTime time.Time, tracked *ProcessLifecycleEventDto) *ProcessLifecycleEventDto {
+	e := *tracked
+	e.Event = event
+	e.Time = eventTime
+	return &e
+}
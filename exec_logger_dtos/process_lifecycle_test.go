@@ -0,0 +1,52 @@
+package exec_logger_dtos
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/process_tree"
+)
+
+func TestProcessLifecycleTracker(t *testing.T) {
+	Convey("Testing ProcessLifecycleTracker", t, func() {
+		t0 := time.Date(2016, 5, 7, 10, 0, 0, 0, time.UTC)
+		sampleAt := func(seconds int, mainProcess *process_tree.Process) *ResourceUsageDto {
+			return &ResourceUsageDto{
+				Time:        t0.Add(time.Duration(seconds) * time.Second),
+				ProcessTree: &process_tree.ProcessTree{MainProcess: mainProcess},
+			}
+		}
+		summarize := func(events []*ProcessLifecycleEventDto) (summaries []string) {
+			for _, e := range events {
+				summaries = append(summaries, e.Event+" "+e.Name)
+			}
+			return
+		}
+
+		tracker := NewProcessLifecycleTracker()
+
+		events := tracker.Track(sampleAt(0, newTestProcess(100, "make", 1, newTestProcess(101, "gcc", 1))))
+		So(summarize(events), ShouldResemble, []string{"spawn make", "spawn gcc"})
+		So(events[1].ParentPid, ShouldEqual, 100)
+		So(events[1].Cmdline, ShouldEqual, "gcc --some-arg")
+
+		So(tracker.Track(sampleAt(1, newTestProcess(100, "make", 1, newTestProcess(101, "gcc", 1)))), ShouldBeEmpty)
+
+		//Tree failed to load, must not be seen as all processes exiting
+		So(tracker.Track(&ResourceUsageDto{Time: t0.Add(2 * time.Second)}), ShouldBeEmpty)
+
+		events = tracker.Track(sampleAt(3, newTestProcess(100, "make", 1, newTestProcess(102, "ld", 1))))
+		So(summarize(events), ShouldResemble, []string{"spawn ld", "exit gcc"})
+		So(events[1].FirstSeen, ShouldResemble, t0)
+		So(events[1].LastSeen, ShouldResemble, t0.Add(time.Second))
+		So(events[1].Time, ShouldResemble, t0.Add(3*time.Second))
+
+		events = tracker.Finish(t0.Add(4 * time.Second))
+		So(summarize(events), ShouldResemble, []string{"exit make", "exit ld"})
+		So(events[0].LastSeen, ShouldResemble, t0.Add(3*time.Second))
+
+		So(tracker.Track(sampleAt(5, newTestProcess(100, "make", 1))), ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,148 @@
+package exec_logger_dtos
+
+import (
+	"sync"
+
+	"github.com/golang-devops/exec-logger/process_tree"
+)
+
+//ResourceSummaryDto holds the aggregated resource usage of all the samples taken during a run
+type ResourceSummaryDto struct {
+	NumSamples      int
+	PeakMemoryKB    int
+	AverageMemoryKB int
+	TotalCPUSeconds int
+	MaxProcessCount int
+	MaxThreadCount  int
+
+	TotalReadBytes  int64 `json:",omitempty"`
+	TotalWriteBytes int64 `json:",omitempty"`
+	MaxOpenFDs      int   `json:",omitempty"`
+	MaxSockets      int   `json:",omitempty"`
+}
+
+//NewResourceSummaryAggregator creates a new ResourceSummaryAggregator
+func NewResourceSummaryAggregator() *ResourceSummaryAggregator {
+	return &ResourceSummaryAggregator{
+		lastUsagePerProcess: make(map[processKey]ProcessResourceUsage),
+	}
+}
+
+//ResourceSummaryAggregator maintains running aggregates of the resource usage samples. It is safe for concurrent use
+type ResourceSummaryAggregator struct {
+	sync.Mutex
+
+	numSamples      int
+	peakMemoryKB    int
+	totalMemoryKB   int64
+	maxProcessCount int
+	maxThreadCount  int
+	maxOpenFDs      int
+	maxSockets      int
+
+	//The cumulative values (cpu and io) of processes that exited, and the last ones of the processes in the latest tree.
+	//The processes are identified by the pid and start time, like in the ResourceUsageCompactor, since pids get reused
+	exitedTotals        ProcessResourceUsage
+	lastUsagePerProcess map[processKey]ProcessResourceUsage
+}
+
+//Add adds a single sample to the aggregates. Samples without any process resource usage (for instance when the process already exited) are ignored
+func (r *ResourceSummaryAggregator) Add(dto *ResourceUsageDto) {
+	if len(dto.ProcessesResourceUsage) == 0 {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	summed := dto.GetSummedProcessesResourceUsage()
+	r.numSamples++
+	r.totalMemoryKB += int64(summed.MemoryKB)
+	if summed.MemoryKB > r.peakMemoryKB {
+		r.peakMemoryKB = summed.MemoryKB
+	}
+	if summed.NumFDs > r.maxOpenFDs {
+		r.maxOpenFDs = summed.NumFDs
+	}
+	if summed.NumSockets > r.maxSockets {
+		r.maxSockets = summed.NumSockets
+	}
+
+	treeKeys := make(map[int]processKey)
+	if dto.ProcessTree != nil {
+		dto.ProcessTree.Walk(func(p *process_tree.Process, parentPid int) {
+			treeKeys[p.Pid] = processKey{pid: p.Pid, startTime: p.CreateTime}
+		})
+	}
+	for _, p := range dto.ProcessesResourceUsage {
+		key, ok := treeKeys[p.Pid]
+		if !ok {
+			key = r.findKeyOfPid(p.Pid)
+		}
+		r.lastUsagePerProcess[key] = *p
+	}
+
+	//The processes that are not in the tree anymore exited, or their pid was reused by a new process
+	if dto.ProcessTree != nil {
+		for key, last := range r.lastUsagePerProcess {
+			if treeKeys[key.pid] != key {
+				r.exitedTotals.CPUSeconds += last.CPUSeconds
+				r.exitedTotals.ReadBytes += last.ReadBytes
+				r.exitedTotals.WriteBytes += last.WriteBytes
+				delete(r.lastUsagePerProcess, key)
+			}
+		}
+	}
+
+	if dto.ProcessTree != nil && dto.ProcessTree.MainProcess != nil {
+		processCount := len(dto.ProcessTree.FlattenedPids())
+		if processCount > r.maxProcessCount {
+			r.maxProcessCount = processCount
+		}
+
+		threadCount := dto.ProcessTree.TotalNumThreads()
+		if threadCount > r.maxThreadCount {
+			r.maxThreadCount = threadCount
+		}
+	}
+}
+
+//findKeyOfPid returns the key of the last process with the pid, for usage of a process that is not in the tree of the sample
+func (r *ResourceSummaryAggregator) findKeyOfPid(pid int) processKey {
+	for key := range r.lastUsagePerProcess {
+		if key.pid == pid {
+			return key
+		}
+	}
+	return processKey{pid: pid}
+}
+
+//Summary returns the current aggregates or nil if no samples were added yet
+func (r *ResourceSummaryAggregator) Summary() *ResourceSummaryDto {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.numSamples == 0 {
+		return nil
+	}
+
+	totals := r.exitedTotals
+	for _, last := range r.lastUsagePerProcess {
+		totals.CPUSeconds += last.CPUSeconds
+		totals.ReadBytes += last.ReadBytes
+		totals.WriteBytes += last.WriteBytes
+	}
+
+	return &ResourceSummaryDto{
+		NumSamples:      r.numSamples,
+		PeakMemoryKB:    r.peakMemoryKB,
+		AverageMemoryKB: int(r.totalMemoryKB / int64(r.numSamples)),
+		TotalCPUSeconds: totals.CPUSeconds,
+		MaxProcessCount: r.maxProcessCount,
+		MaxThreadCount:  r.maxThreadCount,
+		TotalReadBytes:  totals.ReadBytes,
+		TotalWriteBytes: totals.WriteBytes,
+		MaxOpenFDs:      r.maxOpenFDs,
+		MaxSockets:      r.maxSockets,
+	}
+}
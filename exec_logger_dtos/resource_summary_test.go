@@ -0,0 +1,63 @@
+package exec_logger_dtos
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/process_tree"
+)
+
+func TestResourceSummaryAggregator(t *testing.T) {
+	Convey("Testing ResourceSummaryAggregator", t, func() {
+		aggregator := NewResourceSummaryAggregator()
+		So(aggregator.Summary(), ShouldBeNil)
+
+		aggregator.Add(&ResourceUsageDto{
+			ProcessTree: &process_tree.ProcessTree{MainProcess: newTestProcess(100, "make", 1, newTestProcess(101, "gcc", 2))},
+			ProcessesResourceUsage: []*ProcessResourceUsage{
+				&ProcessResourceUsage{Pid: 100, MemoryKB: 1000, CPUSeconds: 1, ReadBytes: 100, NumFDs: 5},
+				&ProcessResourceUsage{Pid: 101, MemoryKB: 2000, CPUSeconds: 5, WriteBytes: 300, NumFDs: 10, NumSockets: 2},
+			},
+		})
+		reusedPid := newTestProcess(101, "as", 1)
+		reusedPid.CreateTime += 500
+		aggregator.Add(&ResourceUsageDto{
+			ProcessTree: &process_tree.ProcessTree{MainProcess: newTestProcess(100, "make", 1, newTestProcess(102, "ld", 4), reusedPid)},
+			ProcessesResourceUsage: []*ProcessResourceUsage{
+				&ProcessResourceUsage{Pid: 100, MemoryKB: 1000, CPUSeconds: 2, ReadBytes: 150, NumFDs: 5},
+				&ProcessResourceUsage{Pid: 102, MemoryKB: 4000, CPUSeconds: 3, ReadBytes: 1000, NumFDs: 3, NumSockets: 1},
+				//Pid 101 was reused by a new process (with another start time), the cpu and io of the previous one must still be counted
+				&ProcessResourceUsage{Pid: 101, MemoryKB: 500, CPUSeconds: 6, WriteBytes: 50, NumFDs: 3},
+			},
+		})
+		//Process already exited, must be ignored
+		aggregator.Add(&ResourceUsageDto{})
+
+		So(aggregator.Summary(), ShouldResemble, &ResourceSummaryDto{
+			NumSamples:      2,
+			PeakMemoryKB:    5500,
+			AverageMemoryKB: 4250,
+			TotalCPUSeconds: 16,
+			MaxProcessCount: 3,
+			MaxThreadCount:  6,
+			TotalReadBytes:  1150,
+			TotalWriteBytes: 350,
+			MaxOpenFDs:      15,
+			MaxSockets:      2,
+		})
+		So(len(aggregator.lastUsagePerProcess), ShouldEqual, 3)
+
+		Convey("Exited processes are moved into the totals", func() {
+			aggregator.Add(&ResourceUsageDto{
+				ProcessTree: &process_tree.ProcessTree{MainProcess: newTestProcess(100, "make", 1)},
+				ProcessesResourceUsage: []*ProcessResourceUsage{
+					&ProcessResourceUsage{Pid: 100, MemoryKB: 1000, CPUSeconds: 4},
+				},
+			})
+			So(len(aggregator.lastUsagePerProcess), ShouldEqual, 1)
+			So(aggregator.Summary().TotalCPUSeconds, ShouldEqual, 18)
+			So(aggregator.Summary().TotalReadBytes, ShouldEqual, 1000)
+		})
+	})
+}
@@ -22,13 +22,24 @@ func (r *ResourceUsageDto) GetSummedProcessesResourceUsage() *ProcessResourceUsa
 	for _, r := range r.ProcessesResourceUsage {
 		p.MemoryKB += r.MemoryKB
 		p.CPUSeconds += r.CPUSeconds
+		p.ReadBytes += r.ReadBytes
+		p.WriteBytes += r.WriteBytes
+		p.NumFDs += r.NumFDs
+		p.NumSockets += r.NumSockets
+		p.NumThreads += r.NumThreads
 	}
 	return p
 }
 
-//ProcessResourceUsage contains resource usage for a single process
+//ProcessResourceUsage contains resource usage for a single process. The io metrics are only filled when recording them was enabled
 type ProcessResourceUsage struct {
 	Pid        int
 	MemoryKB   int
 	CPUSeconds int
+
+	ReadBytes  int64 `json:",omitempty"`
+	WriteBytes int64 `json:",omitempty"`
+	NumFDs     int   `json:",omitempty"`
+	NumSockets int   `json:",omitempty"`
+	NumThreads int   `json:",omitempty"`
 }
@@ -0,0 +1,64 @@
+package exec_logger_dtos
+
+import "github.com/golang-devops/exec-logger/process_tree"
+
+//NewResourceUsageCompactor creates a new ResourceUsageCompactor
+func NewResourceUsageCompactor() *ResourceUsageCompactor {
+	return &ResourceUsageCompactor{
+		seenProcesses: make(map[processKey]ProcessSeenDto),
+	}
+}
+
+//ResourceUsageCompactor splits ResourceUsageDto's into records, only writing the static process details when it was not written before
+type ResourceUsageCompactor struct {
+	seenProcesses map[processKey]ProcessSeenDto
+}
+
+type processKey struct {
+	pid       int
+	startTime int64
+}
+
+//Compact returns the records for a single ResourceUsageDto. It remembers which processes were already seen so must be called in order of the samples
+func (c *ResourceUsageCompactor) Compact(dto *ResourceUsageDto) (records []*ResourceUsageRecordDto) {
+	sample := &ResourceUsageSampleDto{
+		Time:                   dto.Time,
+		CPUPercentage:          dto.CPUPercentage,
+		FreePhysicalMemoryKB:   dto.FreePhysicalMemoryKB,
+		FreeVirtualMemoryKB:    dto.FreeVirtualMemoryKB,
+		ProcessesResourceUsage: dto.ProcessesResourceUsage,
+	}
+
+	//Only keep the processes of the current sample so we do not grow forever with short-lived processes
+	currentProcesses := make(map[processKey]ProcessSeenDto)
+
+	if dto.ProcessTree != nil {
+		dto.ProcessTree.Walk(func(p *process_tree.Process, parentPid int) {
+			seen := newProcessSeenDto(p)
+			key := processKey{pid: seen.Pid, startTime: seen.StartTime}
+
+			if previous, ok := c.seenProcesses[key]; !ok || previous != seen {
+				seenCopy := seen
+				records = append(records, &ResourceUsageRecordDto{
+					Type:        ResourceUsageRecordTypeProcessSeen,
+					ProcessSeen: &seenCopy,
+				})
+			}
+			currentProcesses[key] = seen
+
+			sample.Processes = append(sample.Processes, &ProcessSampleDto{
+				Pid:        p.Pid,
+				ParentPid:  parentPid,
+				NumThreads: p.NumThreads,
+			})
+		})
+		//A failed sample has no tree, then the processes of the previous sample are kept so they are not written again
+		c.seenProcesses = currentProcesses
+	}
+
+	records = append(records, &ResourceUsageRecordDto{
+		Type:   ResourceUsageRecordTypeSample,
+		Sample: sample,
+	})
+	return
+}
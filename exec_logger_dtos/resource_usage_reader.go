@@ -0,0 +1,112 @@
+package exec_logger_dtos
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/golang-devops/exec-logger/process_tree"
+)
+
+//NewResourceUsageReader creates a new ResourceUsageReader reading the resource-usage file content from `r`
+func NewResourceUsageReader(r io.Reader) *ResourceUsageReader {
+	return &ResourceUsageReader{
+		decoder:   json.NewDecoder(r),
+		processes: make(map[int]ProcessSeenDto),
+	}
+}
+
+//ResourceUsageReader reconstructs the full ResourceUsageDto snapshots from the compact records. Lines in the older format (a full ResourceUsageDto per line) are also supported
+type ResourceUsageReader struct {
+	decoder   *json.Decoder
+	processes map[int]ProcessSeenDto
+}
+
+//Next returns the next ResourceUsageDto snapshot or io.EOF when there are no more
+func (r *ResourceUsageReader) Next() (*ResourceUsageDto, error) {
+	for {
+		var rawLine json.RawMessage
+		if err := r.decoder.Decode(&rawLine); err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("Cannot decode resource usage line, error: %s", err.Error())
+		}
+
+		record := &ResourceUsageRecordDto{}
+		if err := json.Unmarshal(rawLine, record); err != nil {
+			return nil, fmt.Errorf("Cannot unmarshal resource usage record, error: %s", err.Error())
+		}
+
+		switch record.Type {
+		case "":
+			dto := &ResourceUsageDto{}
+			if err := json.Unmarshal(rawLine, dto); err != nil {
+				return nil, fmt.Errorf("Cannot unmarshal (old format) resource usage, error: %s", err.Error())
+			}
+			return dto, nil
+		case ResourceUsageRecordTypeProcessSeen:
+			if record.ProcessSeen == nil {
+				return nil, fmt.Errorf("Record of type '%s' is missing the process details", record.Type)
+			}
+			r.processes[record.ProcessSeen.Pid] = *record.ProcessSeen
+		case ResourceUsageRecordTypeSample:
+			if record.Sample == nil {
+				return nil, fmt.Errorf("Record of type '%s' is missing the sample details", record.Type)
+			}
+			return r.expandSample(record.Sample), nil
+		default:
+			return nil, fmt.Errorf("Unknown resource usage record type '%s'", record.Type)
+		}
+	}
+}
+
+func (r *ResourceUsageReader) expandSample(sample *ResourceUsageSampleDto) *ResourceUsageDto {
+	dto := &ResourceUsageDto{
+		Time:                   sample.Time,
+		CPUPercentage:          sample.CPUPercentage,
+		FreePhysicalMemoryKB:   sample.FreePhysicalMemoryKB,
+		FreeVirtualMemoryKB:    sample.FreeVirtualMemoryKB,
+		ProcessesResourceUsage: sample.ProcessesResourceUsage,
+	}
+
+	processesByPid := make(map[int]*process_tree.Process)
+	for _, ps := range sample.Processes {
+		seen := r.processes[ps.Pid]
+		proc := &process_tree.Process{
+			Process:    &os.Process{Pid: ps.Pid},
+			Name:       seen.Name,
+			Exe:        seen.Exe,
+			NumThreads: ps.NumThreads,
+			Cmdline:    seen.Cmdline,
+			CreateTime: seen.StartTime,
+		}
+		processesByPid[ps.Pid] = proc
+
+		if parent, ok := processesByPid[ps.ParentPid]; ok && ps.ParentPid != 0 {
+			parent.Children = append(parent.Children, proc)
+		} else if dto.ProcessTree == nil {
+			dto.ProcessTree = &process_tree.ProcessTree{MainProcess: proc}
+		}
+	}
+
+	return dto
+}
+
+//ReadAllResourceUsage reads all the ResourceUsageDto snapshots from `r`
+func ReadAllResourceUsage(r io.Reader) ([]*ResourceUsageDto, error) {
+	reader := NewResourceUsageReader(r)
+
+	dtos := []*ResourceUsageDto{}
+	for {
+		dto, err := reader.Next()
+		if err == io.EOF {
+			return dtos, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		dtos = append(dtos, dto)
+	}
+}
@@ -0,0 +1,107 @@
+package exec_logger_dtos
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/process_tree"
+)
+
+func newTestProcess(pid int, name string, numThreads int, children ...*process_tree.Process) *process_tree.Process {
+	return &process_tree.Process{
+		Process:    &os.Process{Pid: pid},
+		Name:       name,
+		Exe:        "/usr/bin/" + name,
+		NumThreads: numThreads,
+		Cmdline:    name + " --some-arg",
+		CreateTime: int64(pid * 1000),
+		Children:   children,
+	}
+}
+
+func TestResourceUsageCompactAndRead(t *testing.T) {
+	Convey("Testing ResourceUsageCompactor and ResourceUsageReader", t, func() {
+		sampleTime := time.Date(2016, 5, 7, 10, 0, 0, 0, time.UTC)
+		samples := []*ResourceUsageDto{
+			&ResourceUsageDto{
+				Time:          sampleTime,
+				CPUPercentage: 10,
+				ProcessTree:   &process_tree.ProcessTree{MainProcess: newTestProcess(100, "make", 1, newTestProcess(101, "gcc", 2))},
+				ProcessesResourceUsage: []*ProcessResourceUsage{
+					&ProcessResourceUsage{Pid: 100, MemoryKB: 1000, CPUSeconds: 1},
+					&ProcessResourceUsage{Pid: 101, MemoryKB: 2000, CPUSeconds: 2},
+				},
+			},
+			&ResourceUsageDto{
+				Time:          sampleTime.Add(time.Second),
+				CPUPercentage: 20,
+				ProcessTree:   &process_tree.ProcessTree{MainProcess: newTestProcess(100, "make", 1, newTestProcess(101, "gcc", 3, newTestProcess(102, "as", 1)))},
+				ProcessesResourceUsage: []*ProcessResourceUsage{
+					&ProcessResourceUsage{Pid: 100, MemoryKB: 1000, CPUSeconds: 1},
+					&ProcessResourceUsage{Pid: 101, MemoryKB: 2500, CPUSeconds: 3},
+				},
+			},
+			&ResourceUsageDto{
+				Time:          sampleTime.Add(2 * time.Second),
+				CPUPercentage: 30,
+			},
+			&ResourceUsageDto{
+				Time:          sampleTime.Add(3 * time.Second),
+				CPUPercentage: 40,
+				ProcessTree:   &process_tree.ProcessTree{MainProcess: newTestProcess(100, "make", 1, newTestProcess(101, "gcc", 3, newTestProcess(102, "as", 1)))},
+			},
+		}
+
+		compactor := NewResourceUsageCompactor()
+		var buf bytes.Buffer
+		recordTypesPerSample := [][]string{}
+		for _, sample := range samples {
+			types := []string{}
+			for _, record := range compactor.Compact(sample) {
+				types = append(types, record.Type)
+				jsonBytes, err := json.Marshal(record)
+				So(err, ShouldBeNil)
+				buf.Write(jsonBytes)
+				buf.WriteString("\n")
+			}
+			recordTypesPerSample = append(recordTypesPerSample, types)
+		}
+
+		Convey("Static process details are only written once", func() {
+			So(recordTypesPerSample, ShouldResemble, [][]string{
+				[]string{ResourceUsageRecordTypeProcessSeen, ResourceUsageRecordTypeProcessSeen, ResourceUsageRecordTypeSample},
+				[]string{ResourceUsageRecordTypeProcessSeen, ResourceUsageRecordTypeSample},
+				[]string{ResourceUsageRecordTypeSample},
+				[]string{ResourceUsageRecordTypeSample},
+			})
+		})
+
+		Convey("Reading back reconstructs the full snapshots", func() {
+			readBack, err := ReadAllResourceUsage(&buf)
+			So(err, ShouldBeNil)
+			So(len(readBack), ShouldEqual, len(samples))
+
+			for i := range samples {
+				So(readBack[i].Time.Equal(samples[i].Time), ShouldBeTrue)
+				readBack[i].Time = samples[i].Time
+			}
+			So(readBack, ShouldResemble, samples)
+		})
+
+		Convey("Lines in the old full format are still read", func() {
+			jsonBytes, err := json.Marshal(samples[0])
+			So(err, ShouldBeNil)
+
+			readBack, err := ReadAllResourceUsage(bytes.NewReader(jsonBytes))
+			So(err, ShouldBeNil)
+			So(len(readBack), ShouldEqual, 1)
+			So(readBack[0].ProcessTree.FlattenedPids(), ShouldResemble, []int{100, 101})
+			So(readBack[0].ProcessTree.MainProcess.Children[0].Name, ShouldEqual, "gcc")
+		})
+	})
+}
@@ -0,0 +1,57 @@
+package exec_logger_dtos
+
+import (
+	"time"
+
+	"github.com/golang-devops/exec-logger/process_tree"
+)
+
+const (
+	//ResourceUsageRecordTypeProcessSeen is written once for every new process (unique by Pid and StartTime)
+	ResourceUsageRecordTypeProcessSeen = "process-seen"
+	//ResourceUsageRecordTypeSample is written for every resource usage sample and only references processes by Pid
+	ResourceUsageRecordTypeSample = "sample"
+)
+
+//ResourceUsageRecordDto is a single line in the compact resource-usage file. Only one of `ProcessSeen` and `Sample` is set, depending on the `Type`
+type ResourceUsageRecordDto struct {
+	Type        string
+	ProcessSeen *ProcessSeenDto         `json:",omitempty"`
+	Sample      *ResourceUsageSampleDto `json:",omitempty"`
+}
+
+//ProcessSeenDto holds the static details of a process that do not change between samples
+type ProcessSeenDto struct {
+	Pid       int
+	StartTime int64
+	Name      string
+	Exe       string
+	Cmdline   string
+}
+
+//ResourceUsageSampleDto holds the dynamic values of a single sample
+type ResourceUsageSampleDto struct {
+	Time                   time.Time
+	CPUPercentage          int
+	FreePhysicalMemoryKB   int
+	FreeVirtualMemoryKB    int
+	Processes              []*ProcessSampleDto     `json:",omitempty"`
+	ProcessesResourceUsage []*ProcessResourceUsage `json:",omitempty"`
+}
+
+//ProcessSampleDto is a process in the tree of a sample, the static details are in the last ProcessSeenDto with the same Pid
+type ProcessSampleDto struct {
+	Pid        int
+	ParentPid  int `json:",omitempty"`
+	NumThreads int
+}
+
+func newProcessSeenDto(p *process_tree.Process) ProcessSeenDto {
+	return ProcessSeenDto{
+		Pid:       p.Pid,
+		StartTime: p.CreateTime,
+		Name:      p.Name,
+		Exe:       p.Exe,
+		Cmdline:   p.Cmdline,
+	}
+}
@@ -0,0 +1,27 @@
+package exec_logger_dtos
+
+import "time"
+
+//ScheduleStateDto is the state of all the schedules of the schedule task, rewritten whenever a run starts, finishes or is skipped
+type ScheduleStateDto struct {
+	StartTime time.Time
+	Schedules []*ScheduledJobStateDto
+}
+
+//ScheduledJobStateDto is the state of a single schedule. Its last run files are in the RunDir
+type ScheduledJobStateDto struct {
+	Name         string
+	RunDir       string
+	Running      bool
+	Queued       bool `json:",omitempty"`
+	NextRunTime  time.Time
+	RunCount     int
+	SkippedCount int
+
+	LastRunTime  time.Time
+	LastExitTime time.Time
+	LastDuration string `json:",omitempty"`
+	LastExitCode int
+	LastError    string `json:",omitempty"`
+	LastOutcome  string `json:",omitempty"`
+}
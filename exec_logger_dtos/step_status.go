@@ -0,0 +1,32 @@
+package exec_logger_dtos
+
+import "time"
+
+//StepStatusDto is the exit status of a single step of a multi-step job
+type StepStatusDto struct {
+	Name            string
+	ExitCode        int
+	Error           string `json:",omitempty"`
+	Outcome         string
+	StartTime       time.Time
+	Duration        string `json:",omitempty"`
+	ContinueOnError bool   `json:",omitempty"`
+	AlwaysRun       bool   `json:",omitempty"`
+
+	ResourceLimitExceeded *ResourceLimitExceededDto `json:",omitempty"`
+}
+
+//failedJob returns true if the step failed and was not allowed to
+func (s *StepStatusDto) failedJob() bool {
+	return s.Outcome != ExitOutcomeSuccess && s.Outcome != ExitOutcomeSkipped && !s.ContinueOnError
+}
+
+//GetJobOutcome returns the outcome of the first step that failed the job, or success if none did
+func GetJobOutcome(steps []*StepStatusDto) (string, *ResourceLimitExceededDto) {
+	for _, s := range steps {
+		if s.failedJob() {
+			return s.Outcome, s.ResourceLimitExceeded
+		}
+	}
+	return ExitOutcomeSuccess, nil
+}
@@ -0,0 +1,29 @@
+package exec_logger_dtos
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetJobOutcome(t *testing.T) {
+	Convey("Testing GetJobOutcome", t, func() {
+		limit := &ResourceLimitExceededDto{Limit: "max-memory"}
+		steps := []*StepStatusDto{
+			{Name: "checkout", Outcome: ExitOutcomeSuccess},
+			{Name: "lint", Outcome: ExitOutcomeFailed, ContinueOnError: true},
+		}
+		outcome, limitExceeded := GetJobOutcome(steps)
+		So(outcome, ShouldEqual, ExitOutcomeSuccess)
+		So(limitExceeded, ShouldBeNil)
+
+		steps = append(steps,
+			&StepStatusDto{Name: "build", Outcome: ExitOutcomeResourceLimitExceeded, ResourceLimitExceeded: limit},
+			&StepStatusDto{Name: "test", Outcome: ExitOutcomeSkipped},
+			&StepStatusDto{Name: "cleanup", Outcome: ExitOutcomeFailed, AlwaysRun: true},
+		)
+		outcome, limitExceeded = GetJobOutcome(steps)
+		So(outcome, ShouldEqual, ExitOutcomeResourceLimitExceeded)
+		So(limitExceeded, ShouldEqual, limit)
+	})
+}
@@ -0,0 +1,90 @@
+package exec_logger_dtos
+
+import "time"
+
+//TaskStatusDto is the exit status of a single task of a job with task dependencies. Its own files are in the RunDir
+type TaskStatusDto struct {
+	Name      string
+	DependsOn []string `json:",omitempty"`
+	RunDir    string
+	ExitCode  int
+	Error     string `json:",omitempty"`
+	Outcome   string
+	StartTime time.Time
+	ExitTime  time.Time
+	Duration  string `json:",omitempty"`
+}
+
+func (t *TaskStatusDto) getDuration() time.Duration {
+	if t.StartTime.IsZero() || t.ExitTime.IsZero() {
+		return 0
+	}
+	return t.ExitTime.Sub(t.StartTime)
+}
+
+//CriticalPathDto is the chain of dependent tasks with the longest total duration, which is the least time the job could take
+type CriticalPathDto struct {
+	Tasks    []string
+	Duration string
+}
+
+//GetTasksOutcome returns the outcome of the task that failed first, or success if none did
+func GetTasksOutcome(tasks []*TaskStatusDto) string {
+	var firstFailed *TaskStatusDto
+	for _, t := range tasks {
+		if t.Outcome == ExitOutcomeSuccess || t.Outcome == ExitOutcomeSkipped {
+			continue
+		}
+		if firstFailed == nil || t.ExitTime.Before(firstFailed.ExitTime) {
+			firstFailed = t
+		}
+	}
+	if firstFailed == nil {
+		return ExitOutcomeSuccess
+	}
+	return firstFailed.Outcome
+}
+
+//GetCriticalPath returns the critical path of the tasks, whose dependencies must not have a cycle
+func GetCriticalPath(tasks []*TaskStatusDto) *CriticalPathDto {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	tasksByName := make(map[string]*TaskStatusDto)
+	for _, t := range tasks {
+		tasksByName[t.Name] = t
+	}
+
+	//pathDurations is the longest duration of a chain ending with the task, previous is the dependency on that chain
+	pathDurations := make(map[string]time.Duration)
+	previous := make(map[string]string)
+	var getPathDuration func(t *TaskStatusDto) time.Duration
+	getPathDuration = func(t *TaskStatusDto) time.Duration {
+		if d, ok := pathDurations[t.Name]; ok {
+			return d
+		}
+		var longest time.Duration
+		for _, dependency := range t.DependsOn {
+			if d := getPathDuration(tasksByName[dependency]); d > longest || previous[t.Name] == "" {
+				longest = d
+				previous[t.Name] = dependency
+			}
+		}
+		pathDurations[t.Name] = longest + t.getDuration()
+		return pathDurations[t.Name]
+	}
+
+	var last *TaskStatusDto
+	for _, t := range tasks {
+		if last == nil || getPathDuration(t) > getPathDuration(last) {
+			last = t
+		}
+	}
+
+	criticalPath := &CriticalPathDto{Duration: pathDurations[last.Name].String()}
+	for name := last.Name; name != ""; name = previous[name] {
+		criticalPath.Tasks = append([]string{name}, criticalPath.Tasks...)
+	}
+	return criticalPath
+}
@@ -0,0 +1,39 @@
+package exec_logger_dtos
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTaskStatus(t *testing.T) {
+	Convey("Testing task status", t, func() {
+		t0 := time.Date(2016, 5, 7, 10, 0, 0, 0, time.UTC)
+		newTask := func(name string, start, end time.Duration, outcome string, dependsOn ...string) *TaskStatusDto {
+			return &TaskStatusDto{Name: name, DependsOn: dependsOn, Outcome: outcome, StartTime: t0.Add(start), ExitTime: t0.Add(end)}
+		}
+
+		tasks := []*TaskStatusDto{
+			newTask("build", 0, 10*time.Second, ExitOutcomeSuccess),
+			newTask("unit", 10*time.Second, 40*time.Second, ExitOutcomeSuccess, "build"),
+			newTask("integration", 10*time.Second, 30*time.Second, ExitOutcomeSuccess, "build"),
+			newTask("docs", 0, 45*time.Second, ExitOutcomeSuccess),
+			newTask("package", 40*time.Second, 50*time.Second, ExitOutcomeSuccess, "integration", "unit"),
+		}
+		So(GetTasksOutcome(tasks), ShouldEqual, ExitOutcomeSuccess)
+		So(GetCriticalPath(tasks), ShouldResemble, &CriticalPathDto{
+			Tasks:    []string{"build", "unit", "package"},
+			Duration: "50s",
+		})
+
+		tasks[1].Outcome = ExitOutcomeTimedOut
+		tasks[2].Outcome = ExitOutcomeFailed
+		tasks[4] = &TaskStatusDto{Name: "package", DependsOn: []string{"integration", "unit"}, Outcome: ExitOutcomeSkipped}
+		So(GetTasksOutcome(tasks), ShouldEqual, ExitOutcomeFailed)
+		So(GetCriticalPath(tasks), ShouldResemble, &CriticalPathDto{
+			Tasks:    []string{"docs"},
+			Duration: "45s",
+		})
+	})
+}
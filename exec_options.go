@@ -0,0 +1,50 @@
+package main
+
+import (
+	"time"
+
+	"github.com/golang-devops/exec-logger/log_patterns"
+	"github.com/golang-devops/exec-logger/output_actions"
+	"github.com/golang-devops/exec-logger/output_sinks"
+	"github.com/golang-devops/exec-logger/redaction"
+	"github.com/golang-devops/exec-logger/webhooks"
+)
+
+//execOptions holds the options of the exec task, mostly set from the command-line flags
+type execOptions struct {
+	stdErrIsError       bool
+	runDir              string
+	env                 []string
+	workingDir          string
+	steps               []*jobStep
+	tasks               []*jobTask
+	taskConcurrency     int
+	jobTimeout          time.Duration
+	outputRules         *log_patterns.RuleSet
+	outputTriggers      *output_actions.TriggerSet
+	redactor            *redaction.Redactor
+	webhooks            *webhooks.Notifier
+	sinks               *output_sinks.Definitions
+	timeoutKillDuration time.Duration
+	recordResourceUsage bool
+	recordIOMetrics     bool
+	recordProcesses     bool
+	resourceLimits      resourceLimits
+	logFsync            logFsyncPolicy
+	maxLogSize          int64
+	logRetention        logRetention
+	logSegments         int
+	keepPreviousLog     bool
+	incarnation         int
+	metricsTextfile     string
+	metricsLabels       []metricLabel
+
+	timeoutDiagnostics     bool
+	timeoutQuitSignalNames []string
+	timeoutQuitSignalWait  time.Duration
+}
+
+//mustSampleResourceUsage is true if any of the options require the resource usage to be sampled
+func (e execOptions) mustSampleResourceUsage() bool {
+	return e.recordResourceUsage || e.recordProcesses || e.resourceLimits.isSet() || e.metricsTextfile != ""
+}
@@ -8,11 +8,12 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
-	"strings"
 	"time"
 
 	"github.com/golang-devops/exec-logger/exec_logger_constants"
 	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/process_tree"
+	"github.com/golang-devops/exec-logger/redaction"
 	"github.com/golang-devops/exec-logger/resource_usage"
 )
 
@@ -22,6 +23,16 @@ type execStatusHandler struct {
 	exitedFilePath              string
 	mustAbortFilePath           string
 	recordResourceUsageFilePath string
+	processesFilePath           string
+	diagnosticsFilePath         string
+	snapshotsFilePath           string
+	historyFilePath             string
+
+	resourceUsageCompactor    *exec_logger_dtos.ResourceUsageCompactor
+	resourceSummaryAggregator *exec_logger_dtos.ResourceSummaryAggregator
+	recordIOMetrics           bool
+	processLifecycleTracker   *exec_logger_dtos.ProcessLifecycleTracker
+	redactor                  *redaction.Redactor
 }
 
 func (e *execStatusHandler) writeFile(filePath string, content []byte, mustAppend bool) error {
@@ -31,7 +42,7 @@ func (e *execStatusHandler) writeFile(filePath string, content []byte, mustAppen
 	}
 
 	if !mustAppend {
-		return ioutil.WriteFile(filePath, content, 0600)
+		return writeFileAtomic(filePath, content, 0600)
 	}
 
 	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
@@ -54,7 +65,8 @@ func (e *execStatusHandler) writeJsonFile(filePath string, data interface{}, app
 	return e.writeFile(filePath, jsonBytes, append)
 }
 
-func (e *execStatusHandler) WriteLocalContextFile() error {
+//GetLocalContext returns the (redacted) user and host on which the command runs
+func (e *execStatusHandler) GetLocalContext() *exec_logger_dtos.LocalContextDto {
 	data := &exec_logger_dtos.LocalContextDto{
 		NumCPU: runtime.NumCPU(),
 	}
@@ -71,7 +83,13 @@ func (e *execStatusHandler) WriteLocalContextFile() error {
 		data.HostName = hostName
 	}
 
-	return e.writeJsonFile(e.localContextFilePath, data, false)
+	data.UserName = e.redactor.Redact(data.UserName)
+	data.HostName = e.redactor.Redact(data.HostName)
+	return data
+}
+
+func (e *execStatusHandler) WriteLocalContextFile() error {
+	return e.writeJsonFile(e.localContextFilePath, e.GetLocalContext(), false)
 }
 
 func (e *execStatusHandler) WriteAlive() error {
@@ -79,45 +97,136 @@ func (e *execStatusHandler) WriteAlive() error {
 	return e.writeFile(e.aliveFilePath, []byte(nowTime), false)
 }
 
-func (e *execStatusHandler) WriteResourceUsage(procId int) error {
+//SampleResourceUsage samples the resource usage of the process tree and adds it to the summary. Only when `writeToFile` is true will it be written to the resource-usage file.
+//The warnings are the values that could not be fetched, the sample is still used without them
+func (e *execStatusHandler) SampleResourceUsage(procId int, writeToFile bool) (*exec_logger_dtos.ResourceUsageDto, []string, error) {
 	resourceUsageDTO := &exec_logger_dtos.ResourceUsageDto{}
-	fillWarnings := resource_usage.FillResourceUsage(resourceUsageDTO, procId)
+	warnings := resource_usage.FillResourceUsage(resourceUsageDTO, procId, e.recordIOMetrics)
+	e.redactProcessTree(resourceUsageDTO.ProcessTree)
+	e.resourceSummaryAggregator.Add(resourceUsageDTO)
 
-	fillWarningsMsgPart := ""
-	if len(fillWarnings) > 0 {
-		fillWarningsMsgPart = "Warnings while fetching resource usages: " + strings.Join(fillWarnings, "\\n")
+	if e.processLifecycleTracker != nil {
+		if err := e.writeProcessLifecycleEvents(e.processLifecycleTracker.Track(resourceUsageDTO)); err != nil {
+			return resourceUsageDTO, warnings, err
+		}
 	}
 
-	jsonBytes, err := json.Marshal(resourceUsageDTO)
-	if err != nil {
-		return fmt.Errorf("Cannot marshal resource usage {%+v} to json, error: %s. %s", resourceUsageDTO, err.Error(), fillWarningsMsgPart)
+	if writeToFile {
+		fileContent := ""
+		for _, record := range e.resourceUsageCompactor.Compact(resourceUsageDTO) {
+			jsonBytes, err := json.Marshal(record)
+			if err != nil {
+				return resourceUsageDTO, warnings, fmt.Errorf("Cannot marshal resource usage record {%+v} to json, error: %s", record, err.Error())
+			}
+			fileContent += string(jsonBytes) + "\n"
+		}
+		if err := e.writeFile(e.recordResourceUsageFilePath, []byte(fileContent), true); err != nil {
+			return resourceUsageDTO, warnings, fmt.Errorf("Unable to write resouce-usage file, error: %s", err.Error())
+		}
 	}
-	fileContent := string(jsonBytes) + "\n"
-	if err = e.writeFile(e.recordResourceUsageFilePath, []byte(fileContent), true); err != nil {
-		return fmt.Errorf("Unable to write resouce-usage file, error: %s. %s", err.Error(), fillWarningsMsgPart)
+
+	return resourceUsageDTO, warnings, nil
+}
+
+//redactProcessTree redacts the command lines of the sampled processes, before they are written to the resource-usage and processes files
+func (e *execStatusHandler) redactProcessTree(tree *process_tree.ProcessTree) {
+	if tree == nil {
+		return
 	}
+	tree.Walk(func(p *process_tree.Process, parentPid int) {
+		p.Cmdline = e.redactor.Redact(p.Cmdline)
+	})
+}
+
+//redactDiagnostics redacts the command lines of the processes in the diagnostics before they are written
+func (e *execStatusHandler) redactDiagnostics(dto *exec_logger_dtos.DiagnosticsDto) {
+	for _, p := range dto.Processes {
+		p.Cmdline = e.redactor.Redact(p.Cmdline)
+	}
+}
 
-	//Convert warnings into an error because we successfully wrote the resource file and everything else already.
-	if len(fillWarnings) > 0 {
-		return fmt.Errorf("Everything succeeded but had warnings. So are now shown as an error. %s", fillWarningsMsgPart)
+func (e *execStatusHandler) writeProcessLifecycleEvents(events []*exec_logger_dtos.ProcessLifecycleEventDto) error {
+	if len(events) == 0 {
+		return nil
 	}
 
+	fileContent := ""
+	for _, event := range events {
+		jsonBytes, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("Cannot marshal process lifecycle event {%+v} to json, error: %s", event, err.Error())
+		}
+		fileContent += string(jsonBytes) + "\n"
+	}
+	if err := e.writeFile(e.processesFilePath, []byte(fileContent), true); err != nil {
+		return fmt.Errorf("Unable to write processes file, error: %s", err.Error())
+	}
 	return nil
 }
 
-func (e *execStatusHandler) WriteExitedJson(exitCode int, err error, duration time.Duration) error {
+//FinishProcessLifecycle writes the exit events of the processes that were still alive at the last sample.
+//Tracking starts anew for the next command (of a multi-step job), it must not be called while sampling
+func (e *execStatusHandler) FinishProcessLifecycle(exitTime time.Time) error {
+	if e.processLifecycleTracker == nil {
+		return nil
+	}
+	events := e.processLifecycleTracker.Finish(exitTime)
+	e.processLifecycleTracker = exec_logger_dtos.NewProcessLifecycleTracker()
+	return e.writeProcessLifecycleEvents(events)
+}
+
+func (e *execStatusHandler) WriteDiagnostics(dto *exec_logger_dtos.DiagnosticsDto) error {
+	e.redactDiagnostics(dto)
+	return e.writeJsonFile(e.diagnosticsFilePath, dto, false)
+}
+
+//AppendSnapshot appends the diagnostics snapshot as a single line to the snapshots file
+func (e *execStatusHandler) AppendSnapshot(dto *exec_logger_dtos.DiagnosticsDto) error {
+	e.redactDiagnostics(dto)
+	jsonBytes, err := json.Marshal(dto)
+	if err != nil {
+		return fmt.Errorf("Cannot marshal snapshot to json, error: %s", err.Error())
+	}
+	if err := e.writeFile(e.snapshotsFilePath, append(jsonBytes, '\n'), true); err != nil {
+		return fmt.Errorf("Unable to write snapshots file, error: %s", err.Error())
+	}
+	return nil
+}
+
+//AppendHistory appends the exit of an incarnation of a supervised command as a single line to the history file
+func (e *execStatusHandler) AppendHistory(dto *exec_logger_dtos.IncarnationDto) error {
+	jsonBytes, err := json.Marshal(dto)
+	if err != nil {
+		return fmt.Errorf("Cannot marshal incarnation to json, error: %s", err.Error())
+	}
+	if err := e.writeFile(e.historyFilePath, append(jsonBytes, '\n'), true); err != nil {
+		return fmt.Errorf("Unable to write history file, error: %s", err.Error())
+	}
+	return nil
+}
+
+func (e *execStatusHandler) WriteExitedJson(exitCode int, err error, duration time.Duration, outcome string, limitExceeded *exec_logger_dtos.ResourceLimitExceededDto, steps []*exec_logger_dtos.StepStatusDto, tasks []*exec_logger_dtos.TaskStatusDto) (*exec_logger_dtos.ExitStatusDto, error) {
 	errorStr := ""
 	if err != nil {
-		errorStr = err.Error()
+		errorStr = e.redactor.Redact(err.Error())
 	}
 	data := &exec_logger_dtos.ExitStatusDto{
 		ExitCode: exitCode,
 		Error:    errorStr,
 		ExitTime: time.Now().UTC(),
 		Duration: duration.String(),
+		Outcome:  outcome,
+
+		ResourceLimitExceeded: limitExceeded,
+		Steps:                 steps,
+		Tasks:                 tasks,
+		CriticalPath:          exec_logger_dtos.GetCriticalPath(tasks),
+	}
+	if e.resourceSummaryAggregator != nil {
+		data.ResourceSummary = e.resourceSummaryAggregator.Summary()
 	}
 
-	return e.writeJsonFile(e.exitedFilePath, data, false)
+	return data, e.writeJsonFile(e.exitedFilePath, data, false)
 }
 
 func (e *execStatusHandler) CheckMustAbort() (bool, error) {
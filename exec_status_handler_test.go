@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/process_tree"
+	"github.com/golang-devops/exec-logger/redaction"
+)
+
+func TestExecStatusHandlerRedaction(t *testing.T) {
+	Convey("Testing the redaction of the process command lines", t, func() {
+		redactor := redaction.New()
+		So(redactor.AddValue("s3cr3t"), ShouldBeTrue)
+		handler := &execStatusHandler{redactor: redactor}
+
+		Convey("In the sampled process tree", func() {
+			child := &process_tree.Process{Process: &os.Process{Pid: 101}, Cmdline: "curl -u admin:s3cr3t https://example.com"}
+			tree := &process_tree.ProcessTree{MainProcess: &process_tree.Process{Process: &os.Process{Pid: 100}, Cmdline: "sh -c deploy", Children: []*process_tree.Process{child}}}
+			handler.redactProcessTree(tree)
+			So(tree.MainProcess.Cmdline, ShouldEqual, "sh -c deploy")
+			So(child.Cmdline, ShouldEqual, "curl -u admin:"+redaction.Marker+" https://example.com")
+
+			handler.redactProcessTree(nil)
+		})
+
+		Convey("In the diagnostics", func() {
+			dto := &exec_logger_dtos.DiagnosticsDto{Processes: []*exec_logger_dtos.ProcessDiagnosticsDto{
+				&exec_logger_dtos.ProcessDiagnosticsDto{Pid: 100, Cmdline: "deploy --token s3cr3t"},
+			}}
+			handler.redactDiagnostics(dto)
+			So(dto.Processes[0].Cmdline, ShouldEqual, "deploy --token "+redaction.Marker)
+		})
+	})
+}
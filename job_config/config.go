@@ -0,0 +1,315 @@
+package job_config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	keyCommand    = "command"
+	keyShell      = "shell"
+	keyEnv        = "env"
+	keyWorkingDir = "working-dir"
+	keySteps      = "steps"
+	keyTasks      = "tasks"
+)
+
+//Config is a parsed job file. Apart from the command (or steps or tasks), environment and working directory all keys are the names of flags
+type Config struct {
+	FilePath   string
+	Command    []string
+	Shell      string
+	Env        map[string]string
+	WorkingDir string
+	Steps      []*Step
+	Tasks      []*Task
+	Options    []*Option
+}
+
+//Option is the value of a flag in the job file
+type Option struct {
+	Name  string
+	Value string
+	Line  int
+}
+
+//Schema describes which flags may be used in a job file
+type Schema struct {
+	FlagSet *flag.FlagSet
+	//ReservedFlags can not be set in the job file
+	ReservedFlags []string
+	//ListSeparators are the separators to join the items of flags that are given as a list in the job file. Other flags can not be lists
+	ListSeparators map[string]string
+}
+
+//Load loads and validates a job file
+func Load(filePath string, schema *Schema) (*Config, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read config file '%s', error: %s", filePath, err.Error())
+	}
+	return Parse(filePath, content, schema)
+}
+
+//Parse parses and validates the content of a job file, which is yaml if the file has the .yaml or .yml extension and json
+//otherwise. All problems are returned together as a *ValidationError
+func Parse(filePath string, content []byte, schema *Schema) (*Config, error) {
+	validationErr := &ValidationError{FilePath: filePath}
+
+	var raw map[string]interface{}
+	var lines keyLines
+	if isYamlFile(filePath) {
+		var yamlErr *yamlError
+		if raw, lines, yamlErr = decodeYaml(content); yamlErr != nil {
+			validationErr.add(yamlErr.line, "%s", yamlErr.message)
+			return nil, validationErr
+		}
+	} else {
+		raw = make(map[string]interface{})
+		decoder := json.NewDecoder(strings.NewReader(string(content)))
+		decoder.UseNumber()
+		if err := decoder.Decode(&raw); err != nil {
+			validationErr.add(lineOfJsonError(content, err), "%s", err.Error())
+			return nil, validationErr
+		}
+		lines = findKeyLines(content)
+	}
+
+	config := &Config{FilePath: filePath}
+	keys := []string{}
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		line := lines.line(key)
+		value := raw[key]
+
+		switch key {
+		case keyCommand:
+			config.Command = validateStringList(validationErr, line, key, value)
+		case keyShell:
+			config.Shell = validateString(validationErr, line, key, value)
+		case keyWorkingDir:
+			config.WorkingDir = validateString(validationErr, line, key, value)
+		case keyEnv:
+			config.Env = validateStringMap(validationErr, line, key, value)
+		case keySteps:
+			config.Steps = validateSteps(validationErr, lines, value)
+		case keyTasks:
+			config.Tasks = validateTasks(validationErr, lines, value)
+		default:
+			if option := schema.validateOption(validationErr, line, key, value); option != nil {
+				config.Options = append(config.Options, option)
+			}
+		}
+	}
+
+	if len(config.Command) > 0 && config.Shell != "" {
+		validationErr.add(lines.line(keyShell), "only one of '%s' and '%s' can be given", keyCommand, keyShell)
+	}
+	if len(config.Steps) > 0 && (len(config.Command) > 0 || config.Shell != "") {
+		validationErr.add(lines.line(keySteps), "'%s' can not be combined with '%s' or '%s'", keySteps, keyCommand, keyShell)
+	}
+	if len(config.Tasks) > 0 && (len(config.Command) > 0 || config.Shell != "" || len(config.Steps) > 0) {
+		validationErr.add(lines.line(keyTasks), "'%s' can not be combined with '%s', '%s' or '%s'", keyTasks, keyCommand, keyShell, keySteps)
+	}
+
+	sort.Sort(optionsByLine(config.Options))
+	if len(validationErr.Problems) > 0 {
+		sort.Stable(problemsByLine(validationErr.Problems))
+		return nil, validationErr
+	}
+	return config, nil
+}
+
+func (s *Schema) isReserved(name string) bool {
+	for _, r := range s.ReservedFlags {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) validateOption(validationErr *ValidationError, line int, key string, value interface{}) *Option {
+	f := s.FlagSet.Lookup(key)
+	if f == nil || s.isReserved(key) {
+		validationErr.add(line, "unknown key '%s'", key)
+		return nil
+	}
+
+	var defaultValue interface{}
+	if getter, ok := f.Value.(flag.Getter); ok {
+		defaultValue = getter.Get()
+	}
+
+	strValue := ""
+	switch v := value.(type) {
+	case bool:
+		if _, ok := defaultValue.(bool); !ok {
+			validationErr.add(line, "'%s' must be a %s, not a boolean", key, typeDescription(defaultValue))
+			return nil
+		}
+		strValue = strconv.FormatBool(v)
+
+	case json.Number:
+		if _, ok := defaultValue.(int); !ok {
+			validationErr.add(line, "'%s' must be a %s, not a number", key, typeDescription(defaultValue))
+			return nil
+		}
+		if _, err := strconv.Atoi(v.String()); err != nil {
+			validationErr.add(line, "'%s' must be a whole number, got %s", key, v.String())
+			return nil
+		}
+		strValue = v.String()
+
+	case string:
+		switch defaultValue.(type) {
+		case bool, int:
+			validationErr.add(line, "'%s' must be a %s, not a string", key, typeDescription(defaultValue))
+			return nil
+		case time.Duration:
+			if _, err := time.ParseDuration(v); err != nil {
+				validationErr.add(line, "'%s' must be a duration like 90s or 10m, got '%s'", key, v)
+				return nil
+			}
+		}
+		strValue = v
+
+	case []interface{}, map[string]interface{}:
+		separator, ok := s.ListSeparators[key]
+		if !ok {
+			validationErr.add(line, "'%s' must be a %s, not a list or object", key, typeDescription(defaultValue))
+			return nil
+		}
+		var items []string
+		if m, isMap := v.(map[string]interface{}); isMap {
+			for k, mv := range validateStringMap(validationErr, line, key, m) {
+				items = append(items, k+"="+mv)
+			}
+			sort.Strings(items)
+		} else {
+			items = validateStringList(validationErr, line, key, v)
+		}
+		strValue = strings.Join(items, separator)
+
+	default:
+		validationErr.add(line, "'%s' has an unsupported value", key)
+		return nil
+	}
+
+	return &Option{Name: key, Value: strValue, Line: line}
+}
+
+func typeDescription(defaultValue interface{}) string {
+	switch defaultValue.(type) {
+	case bool:
+		return "boolean"
+	case int:
+		return "whole number"
+	case time.Duration:
+		return "duration string"
+	default:
+		return "string"
+	}
+}
+
+func validateString(validationErr *ValidationError, line int, key string, value interface{}) string {
+	s, ok := value.(string)
+	if !ok {
+		validationErr.add(line, "'%s' must be a string", key)
+	}
+	return s
+}
+
+func validateStringList(validationErr *ValidationError, line int, key string, value interface{}) (list []string) {
+	items, ok := value.([]interface{})
+	if !ok {
+		validationErr.add(line, "'%s' must be a list of strings", key)
+		return nil
+	}
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			validationErr.add(line, "item %d of '%s' must be a string", i+1, key)
+			continue
+		}
+		list = append(list, s)
+	}
+	return
+}
+
+func validateStringMap(validationErr *ValidationError, line int, key string, value interface{}) map[string]string {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		validationErr.add(line, "'%s' must be an object with string values", key)
+		return nil
+	}
+	result := make(map[string]string)
+	for k, v := range m {
+		switch typed := v.(type) {
+		case string:
+			result[k] = typed
+		case json.Number:
+			result[k] = typed.String()
+		case bool:
+			result[k] = strconv.FormatBool(typed)
+		default:
+			validationErr.add(line, "'%s' of '%s' must be a string", k, key)
+		}
+	}
+	return result
+}
+
+//RunArgs returns the command-line to run, a shell string is run with `sh -c` (or `cmd /C` on windows)
+func (c *Config) RunArgs() []string {
+	if c.Shell == "" {
+		return c.Command
+	}
+	switch strings.ToLower(runtime.GOOS) {
+	case "windows":
+		return []string{"cmd", "/C", c.Shell}
+	default:
+		return []string{"/bin/sh", "-c", c.Shell}
+	}
+}
+
+//EnvList returns the environment variables as sorted KEY=VALUE pairs
+func (c *Config) EnvList() (env []string) {
+	for k, v := range c.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return
+}
+
+//ApplyToFlags sets the flags of the options, except the ones that were explicitly set on the command-line
+func (c *Config) ApplyToFlags(flagSet *flag.FlagSet) error {
+	explicitlySet := make(map[string]bool)
+	flagSet.Visit(func(f *flag.Flag) { explicitlySet[f.Name] = true })
+
+	for _, option := range c.Options {
+		if explicitlySet[option.Name] {
+			continue
+		}
+		if err := flagSet.Set(option.Name, option.Value); err != nil {
+			return fmt.Errorf("%s:%d: invalid value for '%s', error: %s", c.FilePath, option.Line, option.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+type optionsByLine []*Option
+
+func (o optionsByLine) Len() int           { return len(o) }
+func (o optionsByLine) Swap(i, j int)      { o[i], o[j] = o[j], o[i] }
+func (o optionsByLine) Less(i, j int) bool { return o[i].Line < o[j].Line }
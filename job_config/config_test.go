@@ -0,0 +1,208 @@
+package job_config
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newTestSchema() *Schema {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.String("task", "", "")
+	flagSet.Bool("stderr-is-error", false, "")
+	flagSet.Duration("timeout-kill", 0, "")
+	flagSet.Int("max-processes", 0, "")
+	flagSet.String("parse_patterns", "", "")
+	flagSet.String("metrics-labels", "", "")
+
+	return &Schema{
+		FlagSet:       flagSet,
+		ReservedFlags: []string{"task"},
+		ListSeparators: map[string]string{
+			"parse_patterns": "[{|}]",
+			"metrics-labels": ",",
+		},
+	}
+}
+
+func TestConfig(t *testing.T) {
+	Convey("Testing job config", t, func() {
+		Convey("A valid config is applied to the flags that were not set explicitly", func() {
+			schema := newTestSchema()
+			config, err := Parse("job.json", []byte(`{
+				"command": ["ping", "127.0.0.1"],
+				"env": {"LANG": "C"},
+				"working-dir": "/tmp",
+				"timeout-kill": "10m",
+				"stderr-is-error": true,
+				"max-processes": 20,
+				"parse_patterns": ["ERROR: (.*)", "FATAL"],
+				"metrics-labels": {"job": "nightly", "branch": "master"}
+			}`), schema)
+			So(err, ShouldBeNil)
+			So(config.RunArgs(), ShouldResemble, []string{"ping", "127.0.0.1"})
+			So(config.EnvList(), ShouldResemble, []string{"LANG=C"})
+			So(config.WorkingDir, ShouldEqual, "/tmp")
+
+			So(schema.FlagSet.Parse([]string{"-max-processes", "5"}), ShouldBeNil)
+			So(config.ApplyToFlags(schema.FlagSet), ShouldBeNil)
+
+			get := func(name string) interface{} { return schema.FlagSet.Lookup(name).Value.(flag.Getter).Get() }
+			So(get("timeout-kill"), ShouldEqual, 10*time.Minute)
+			So(get("stderr-is-error"), ShouldEqual, true)
+			So(get("max-processes"), ShouldEqual, 5)
+			So(get("parse_patterns"), ShouldEqual, "ERROR: (.*)[{|}]FATAL")
+			So(get("metrics-labels"), ShouldEqual, "branch=master,job=nightly")
+		})
+
+		Convey("All problems are reported with their line numbers", func() {
+			_, err := Parse("job.json", []byte(`{
+  "command": ["make"],
+  "shell": "make all",
+  "timout-kill": "10m",
+  "task": "exec",
+  "stderr-is-error": "yes",
+  "max-processes": 2.5,
+  "timeout-kill": "soon"
+}`), newTestSchema())
+			So(err, ShouldNotBeNil)
+			validationErr, ok := err.(*ValidationError)
+			So(ok, ShouldBeTrue)
+			So(validationErr.Lines(), ShouldResemble, []string{
+				"job.json:3: only one of 'command' and 'shell' can be given",
+				"job.json:4: unknown key 'timout-kill'",
+				"job.json:5: unknown key 'task'",
+				"job.json:6: 'stderr-is-error' must be a boolean, not a string",
+				"job.json:7: 'max-processes' must be a whole number, got 2.5",
+				"job.json:8: 'timeout-kill' must be a duration like 90s or 10m, got 'soon'",
+			})
+		})
+
+		Convey("Tasks are validated with their dependencies", func() {
+			config, err := Parse("job.json", []byte(`{
+  "tasks": [
+    { "name": "build", "shell": "make all" },
+    { "name": "unit", "shell": "make unit", "depends-on": ["build"] },
+    { "name": "package", "command": ["make", "dist"], "depends-on": ["build", "unit"], "timeout-kill": "5m" }
+  ]
+}`), newTestSchema())
+			So(err, ShouldBeNil)
+			So(len(config.Tasks), ShouldEqual, 3)
+			So(config.Tasks[2].DependsOn, ShouldResemble, []string{"build", "unit"})
+			So(config.Tasks[2].TimeoutKill, ShouldEqual, 5*time.Minute)
+
+			_, err = Parse("job.json", []byte(`{
+  "tasks": [
+    { "name": "build", "shell": "make all", "depends-on": ["package"] },
+    { "name": "unit", "shell": "make unit", "depends-on": ["build"] },
+    { "name": "package", "shell": "make dist", "depends-on": ["unit"] }
+  ]
+}`), newTestSchema())
+			So(err, ShouldNotBeNil)
+			So(err.(*ValidationError).Lines(), ShouldResemble, []string{
+				"job.json:2: the tasks have a dependency cycle build -> package -> unit -> build",
+			})
+
+			_, err = Parse("job.json", []byte(`{
+  "shell": "make",
+  "tasks": [
+    { "name": "build", "shell": "make all", "depends-on": ["checkout"] },
+    { "shell": "make unit" }
+  ]
+}`), newTestSchema())
+			So(err, ShouldNotBeNil)
+			So(err.(*ValidationError).Lines(), ShouldResemble, []string{
+				"job.json:3: 'tasks' can not be combined with 'command', 'shell' or 'steps'",
+				"job.json:4: task 'build' depends on the unknown task 'checkout'",
+				"job.json:5: task 2 must have a 'name'",
+			})
+
+			_, err = Parse("job.json", []byte(`{
+  "tasks": [
+    { "name": ".", "shell": "make all" },
+    { "name": "..", "shell": "make unit" },
+    { "name": "unit/fast", "shell": "make unit-fast" }
+  ]
+}`), newTestSchema())
+			So(err, ShouldNotBeNil)
+			So(err.(*ValidationError).Lines(), ShouldResemble, []string{
+				"job.json:3: task 1 can not be named '.'",
+				"job.json:4: task 2 can not be named '..'",
+				"job.json:5: task 3 has a name 'unit/fast' with a path separator",
+			})
+		})
+
+		Convey("The problems of steps point at the step or its key", func() {
+			_, err := Parse("job.json", []byte(`{
+  "steps": [
+    { "name": "build", "shell": "make all", "timeout-kill": "10m" },
+    {
+      "name": "test",
+      "shell": "make test",
+      "timeout-kill": "soon"
+    },
+    "make deploy",
+    { "name": "build", "always-run": true }
+  ],
+  "timeout-kill": "forever"
+}`), newTestSchema())
+			So(err, ShouldNotBeNil)
+			So(err.(*ValidationError).Lines(), ShouldResemble, []string{
+				"job.json:7: 'steps[2].timeout-kill' must be a duration like 90s or 10m, got 'soon'",
+				"job.json:9: step 3 must be an object",
+				"job.json:10: step 4 has a duplicate name 'build'",
+				"job.json:10: step 4 must have a 'command' or 'shell'",
+				"job.json:12: 'timeout-kill' must be a duration like 90s or 10m, got 'forever'",
+			})
+		})
+
+		Convey("Syntax errors have the line number", func() {
+			_, err := Parse("job.json", []byte("{\n  \"command\": [\"make\"],\n  \"shell\" \"x\"\n}"), newTestSchema())
+			So(err, ShouldNotBeNil)
+			So(strings.HasPrefix(err.(*ValidationError).Lines()[0], "job.json:3: "), ShouldBeTrue)
+		})
+
+		Convey("A yaml job file gives the same values and line numbers", func() {
+			schema := newTestSchema()
+			config, err := Parse("job.yaml", []byte(`command: [ping, 127.0.0.1]
+env:
+  LANG: C
+timeout-kill: 10m
+stderr-is-error: true
+max-processes: 20
+parse_patterns:
+  - "ERROR: (.*)"
+  - FATAL
+`), schema)
+			So(err, ShouldBeNil)
+			So(config.RunArgs(), ShouldResemble, []string{"ping", "127.0.0.1"})
+			So(config.EnvList(), ShouldResemble, []string{"LANG=C"})
+			So(config.ApplyToFlags(schema.FlagSet), ShouldBeNil)
+			So(schema.FlagSet.Lookup("max-processes").Value.String(), ShouldEqual, "20")
+			So(schema.FlagSet.Lookup("parse_patterns").Value.String(), ShouldEqual, "ERROR: (.*)[{|}]FATAL")
+
+			_, err = Parse("job.yml", []byte(`steps:
+  - name: build
+    shell: make all
+  - name: test
+    shell: make test
+    timeout-kill: soon
+max-processes: 2.5
+timout-kill: 10m
+`), newTestSchema())
+			So(err, ShouldNotBeNil)
+			So(err.(*ValidationError).Lines(), ShouldResemble, []string{
+				"job.yml:6: 'steps[2].timeout-kill' must be a duration like 90s or 10m, got 'soon'",
+				"job.yml:7: 'max-processes' must be a whole number, got 2.5",
+				"job.yml:8: unknown key 'timout-kill'",
+			})
+
+			_, err = Parse("job.yaml", []byte("command: [make\nshell: x\n"), newTestSchema())
+			So(err, ShouldNotBeNil)
+			So(err.(*ValidationError).Lines()[0], ShouldStartWith, "job.yaml:")
+		})
+	})
+}
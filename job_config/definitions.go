@@ -0,0 +1,107 @@
+package job_config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+//Definitions are the contents of the json files next to the job config, like the pattern rules, actions, sinks,
+//schedules and webhooks files. Compile validates them after decoding and returns all the problems as *DefinitionProblems
+type Definitions interface {
+	Compile() error
+}
+
+type definitionProblem struct {
+	path    string
+	message string
+}
+
+//DefinitionProblems collects the problems of definitions. Every problem has the json path of the definition it belongs
+//to, like `Rules[2]` or `Rules[2].Pattern`, which gives its line when the definitions are loaded from a file
+type DefinitionProblems struct {
+	Kind     string
+	problems []*definitionProblem
+}
+
+//NewDefinitionProblems returns an empty problem list for the kind of definitions, like `rules`
+func NewDefinitionProblems(kind string) *DefinitionProblems {
+	return &DefinitionProblems{Kind: kind}
+}
+
+//Add adds a problem of the definition at the path
+func (d *DefinitionProblems) Add(path string, format string, args ...interface{}) {
+	d.problems = append(d.problems, &definitionProblem{path: path, message: fmt.Sprintf(format, args...)})
+}
+
+//Err returns the problems as an error, or nil if there are none
+func (d *DefinitionProblems) Err() error {
+	if len(d.problems) == 0 {
+		return nil
+	}
+	return d
+}
+
+func (d *DefinitionProblems) Error() string {
+	messages := []string{}
+	for _, p := range d.problems {
+		messages = append(messages, p.message)
+	}
+	return fmt.Sprintf("%d problems in the %s: %s", len(d.problems), d.Kind, strings.Join(messages, "; "))
+}
+
+//LoadDefinitions reads the json file into the definitions and compiles them, see ParseDefinitions
+func LoadDefinitions(filePath string, definitions Definitions) error {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("Cannot read file '%s', error: %s", filePath, err.Error())
+	}
+	return ParseDefinitions(filePath, content, definitions)
+}
+
+//ParseDefinitions decodes the json content into the definitions and compiles them. The problems are returned together
+//as a *ValidationError, with the lines of the definitions they belong to
+func ParseDefinitions(filePath string, content []byte, definitions Definitions) error {
+	validationErr := &ValidationError{FilePath: filePath}
+	if err := json.Unmarshal(content, definitions); err != nil {
+		validationErr.add(lineOfJsonError(content, err), "%s", err.Error())
+		return validationErr
+	}
+
+	err := definitions.Compile()
+	if err == nil {
+		return nil
+	}
+	definitionProblems, ok := err.(*DefinitionProblems)
+	if !ok {
+		validationErr.add(1, "%s", err.Error())
+		return validationErr
+	}
+
+	lines := findKeyLines(content)
+	for _, p := range definitionProblems.problems {
+		validationErr.add(lines.definitionLine(p.path), "%s", p.message)
+	}
+	sort.Stable(problemsByLine(validationErr.Problems))
+	return validationErr
+}
+
+//definitionLine returns the line of the path, or of the closest parent in the content. The keys are matched without
+//case like the json decoder does
+func (k keyLines) definitionLine(path string) int {
+	for path != "" {
+		for key, line := range k {
+			if strings.EqualFold(key, path) {
+				return line
+			}
+		}
+		if i := strings.LastIndexAny(path, ".["); i >= 0 {
+			path = path[:i]
+		} else {
+			path = ""
+		}
+	}
+	return 1
+}
@@ -0,0 +1,60 @@
+package job_config
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testItem struct {
+	Name  string
+	Count int
+}
+
+type testDefinitions struct {
+	Items []*testItem
+}
+
+func (t *testDefinitions) Compile() error {
+	problems := NewDefinitionProblems("items")
+	for i, item := range t.Items {
+		if item.Name == "" {
+			problems.Add(fmt.Sprintf("Items[%d].Name", i+1), "item %d has no name", i+1)
+		}
+		if item.Count < 0 {
+			problems.Add(fmt.Sprintf("Items[%d].Count", i+1), "item %d has a negative count", i+1)
+		}
+	}
+	return problems.Err()
+}
+
+func TestDefinitions(t *testing.T) {
+	Convey("Testing definitions", t, func() {
+		Convey("Valid definitions are compiled", func() {
+			definitions := &testDefinitions{}
+			So(ParseDefinitions("items.json", []byte(`{"Items": [{"Name": "a"}]}`), definitions), ShouldBeNil)
+			So(definitions.Items[0].Name, ShouldEqual, "a")
+		})
+
+		Convey("The problems have the line of their key, or of the definition if the key is not in the file", func() {
+			err := ParseDefinitions("items.json", []byte("{\n  \"items\": [\n    {\"Name\": \"a\",\n     \"count\": -1},\n    {}\n  ]\n}"), &testDefinitions{})
+			So(err, ShouldNotBeNil)
+			So(err.(*ValidationError).Lines(), ShouldResemble, []string{
+				"items.json:4: item 1 has a negative count",
+				"items.json:5: item 2 has no name",
+			})
+		})
+
+		Convey("Compiling without a file counts the problems", func() {
+			definitions := &testDefinitions{Items: []*testItem{{Count: -1}}}
+			So(definitions.Compile().Error(), ShouldEqual, "2 problems in the items: item 1 has no name; item 1 has a negative count")
+		})
+
+		Convey("Syntax errors have the line number", func() {
+			err := ParseDefinitions("items.json", []byte("{\n  \"Items\": [\n    {\"Name\" \"a\"}\n  ]\n}"), &testDefinitions{})
+			So(err, ShouldNotBeNil)
+			So(err.(*ValidationError).Lines()[0], ShouldStartWith, "items.json:3: ")
+		})
+	})
+}
@@ -0,0 +1,102 @@
+package job_config
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+//Step is a single command of a multi-step job
+type Step struct {
+	Name            string
+	Command         []string
+	Shell           string
+	Env             map[string]string
+	WorkingDir      string
+	TimeoutKill     time.Duration
+	ContinueOnError bool
+	AlwaysRun       bool
+}
+
+//RunArgs returns the command-line of the step, a shell string is run like the Config.Shell
+func (s *Step) RunArgs() []string {
+	return (&Config{Command: s.Command, Shell: s.Shell}).RunArgs()
+}
+
+func validateSteps(validationErr *ValidationError, lines keyLines, value interface{}) (steps []*Step) {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		validationErr.add(lines.line(keySteps), "'steps' must be a non-empty list of objects")
+		return nil
+	}
+
+	seenNames := make(map[string]bool)
+	for i, item := range items {
+		stepDesc := fmt.Sprintf("step %d", i+1)
+		line := lines.line(fmt.Sprintf("%s[%d]", keySteps, i+1))
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			validationErr.add(line, "%s must be an object", stepDesc)
+			continue
+		}
+
+		keys := []string{}
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		step := &Step{Name: fmt.Sprintf("step-%d", i+1)}
+		for _, key := range keys {
+			v := m[key]
+			keyDesc := fmt.Sprintf("steps[%d].%s", i+1, key)
+			keyLine := lines.line(keyDesc)
+			switch key {
+			case "name":
+				step.Name = validateString(validationErr, keyLine, keyDesc, v)
+			case keyCommand:
+				step.Command = validateStringList(validationErr, keyLine, keyDesc, v)
+			case keyShell:
+				step.Shell = validateString(validationErr, keyLine, keyDesc, v)
+			case keyEnv:
+				step.Env = validateStringMap(validationErr, keyLine, keyDesc, v)
+			case keyWorkingDir:
+				step.WorkingDir = validateString(validationErr, keyLine, keyDesc, v)
+			case "timeout-kill":
+				s := validateString(validationErr, keyLine, keyDesc, v)
+				if d, err := time.ParseDuration(s); s != "" && (err != nil || d < 0) {
+					validationErr.add(keyLine, "'%s' must be a duration like 90s or 10m, got '%s'", keyDesc, s)
+				} else {
+					step.TimeoutKill = d
+				}
+			case "continue-on-error":
+				step.ContinueOnError = validateBool(validationErr, keyLine, keyDesc, v)
+			case "always-run":
+				step.AlwaysRun = validateBool(validationErr, keyLine, keyDesc, v)
+			default:
+				validationErr.add(keyLine, "unknown key '%s' in %s", key, stepDesc)
+			}
+		}
+
+		if seenNames[step.Name] {
+			validationErr.add(line, "%s has a duplicate name '%s'", stepDesc, step.Name)
+		}
+		seenNames[step.Name] = true
+
+		if len(step.Command) > 0 && step.Shell != "" {
+			validationErr.add(line, "%s can only have one of '%s' and '%s'", stepDesc, keyCommand, keyShell)
+		} else if len(step.Command) == 0 && step.Shell == "" {
+			validationErr.add(line, "%s must have a '%s' or '%s'", stepDesc, keyCommand, keyShell)
+		}
+		steps = append(steps, step)
+	}
+	return
+}
+
+func validateBool(validationErr *ValidationError, line int, key string, value interface{}) bool {
+	b, ok := value.(bool)
+	if !ok {
+		validationErr.add(line, "'%s' must be a boolean", key)
+	}
+	return b
+}
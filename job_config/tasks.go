@@ -0,0 +1,160 @@
+package job_config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Task is a single command of a job whose tasks run in parallel once the tasks they depend on succeeded
+type Task struct {
+	Name        string
+	Command     []string
+	Shell       string
+	Env         map[string]string
+	WorkingDir  string
+	TimeoutKill time.Duration
+	DependsOn   []string
+}
+
+//RunArgs returns the command-line of the task, a shell string is run like the Config.Shell
+func (t *Task) RunArgs() []string {
+	return (&Config{Command: t.Command, Shell: t.Shell}).RunArgs()
+}
+
+func validateTasks(validationErr *ValidationError, lines keyLines, value interface{}) (tasks []*Task) {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		validationErr.add(lines.line(keyTasks), "'%s' must be a non-empty list of objects", keyTasks)
+		return nil
+	}
+
+	tasksByName := make(map[string]*Task)
+	dependsOnLines := make(map[*Task]int)
+	for i, item := range items {
+		taskDesc := fmt.Sprintf("task %d", i+1)
+		line := lines.line(fmt.Sprintf("%s[%d]", keyTasks, i+1))
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			validationErr.add(line, "%s must be an object", taskDesc)
+			continue
+		}
+
+		keys := []string{}
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		task := &Task{}
+		for _, key := range keys {
+			v := m[key]
+			keyDesc := fmt.Sprintf("tasks[%d].%s", i+1, key)
+			keyLine := lines.line(keyDesc)
+			switch key {
+			case "name":
+				task.Name = validateString(validationErr, keyLine, keyDesc, v)
+			case keyCommand:
+				task.Command = validateStringList(validationErr, keyLine, keyDesc, v)
+			case keyShell:
+				task.Shell = validateString(validationErr, keyLine, keyDesc, v)
+			case keyEnv:
+				task.Env = validateStringMap(validationErr, keyLine, keyDesc, v)
+			case keyWorkingDir:
+				task.WorkingDir = validateString(validationErr, keyLine, keyDesc, v)
+			case "timeout-kill":
+				s := validateString(validationErr, keyLine, keyDesc, v)
+				if d, err := time.ParseDuration(s); s != "" && (err != nil || d < 0) {
+					validationErr.add(keyLine, "'%s' must be a duration like 90s or 10m, got '%s'", keyDesc, s)
+				} else {
+					task.TimeoutKill = d
+				}
+			case "depends-on":
+				task.DependsOn = validateStringList(validationErr, keyLine, keyDesc, v)
+				dependsOnLines[task] = keyLine
+			default:
+				validationErr.add(keyLine, "unknown key '%s' in %s", key, taskDesc)
+			}
+		}
+
+		if task.Name == "" {
+			validationErr.add(line, "%s must have a 'name'", taskDesc)
+		} else if strings.ContainsAny(task.Name, `/\`) {
+			//The name is used as the directory of the task
+			validationErr.add(line, "%s has a name '%s' with a path separator", taskDesc, task.Name)
+		} else if task.Name == "." || task.Name == ".." {
+			validationErr.add(line, "%s can not be named '%s'", taskDesc, task.Name)
+		} else if tasksByName[task.Name] != nil {
+			validationErr.add(line, "%s has a duplicate name '%s'", taskDesc, task.Name)
+		} else {
+			tasksByName[task.Name] = task
+		}
+
+		if len(task.Command) > 0 && task.Shell != "" {
+			validationErr.add(line, "%s can only have one of '%s' and '%s'", taskDesc, keyCommand, keyShell)
+		} else if len(task.Command) == 0 && task.Shell == "" {
+			validationErr.add(line, "%s must have a '%s' or '%s'", taskDesc, keyCommand, keyShell)
+		}
+		tasks = append(tasks, task)
+	}
+
+	unknownDependency := false
+	for _, task := range tasks {
+		for _, dependency := range task.DependsOn {
+			if tasksByName[dependency] == nil {
+				validationErr.add(dependsOnLines[task], "task '%s' depends on the unknown task '%s'", task.Name, dependency)
+				unknownDependency = true
+			}
+		}
+	}
+	if !unknownDependency {
+		if cycle := findDependencyCycle(tasks, tasksByName); len(cycle) > 0 {
+			validationErr.add(lines.line(keyTasks), "the tasks have a dependency cycle %s", strings.Join(cycle, " -> "))
+		}
+	}
+	return
+}
+
+//findDependencyCycle returns the names of the tasks of the first cycle found, with the first task repeated at the end
+func findDependencyCycle(tasks []*Task, tasksByName map[string]*Task) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make(map[string]int)
+	path := []string{}
+
+	var visit func(task *Task) []string
+	visit = func(task *Task) []string {
+		states[task.Name] = visiting
+		path = append(path, task.Name)
+		for _, dependency := range task.DependsOn {
+			switch states[dependency] {
+			case visiting:
+				for i, name := range path {
+					if name == dependency {
+						return append(append([]string{}, path[i:]...), dependency)
+					}
+				}
+			case unvisited:
+				if cycle := visit(tasksByName[dependency]); len(cycle) > 0 {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		states[task.Name] = visited
+		return nil
+	}
+
+	for _, task := range tasks {
+		if states[task.Name] == unvisited {
+			if cycle := visit(task); len(cycle) > 0 {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package job_config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+//Problem is a single validation problem of a job file
+type Problem struct {
+	Line    int
+	Message string
+}
+
+//ValidationError holds all the problems found in a job file
+type ValidationError struct {
+	FilePath string
+	Problems []*Problem
+}
+
+func (v *ValidationError) add(line int, format string, args ...interface{}) {
+	v.Problems = append(v.Problems, &Problem{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+//Lines returns every problem formatted as `file:line: message`
+func (v *ValidationError) Lines() (lines []string) {
+	for _, p := range v.Problems {
+		lines = append(lines, fmt.Sprintf("%s:%d: %s", v.FilePath, p.Line, p.Message))
+	}
+	return
+}
+
+func (v *ValidationError) Error() string {
+	return fmt.Sprintf("Invalid config file '%s' with %d problems:\n%s", v.FilePath, len(v.Problems), strings.Join(v.Lines(), "\n"))
+}
+
+type problemsByLine []*Problem
+
+func (p problemsByLine) Len() int           { return len(p) }
+func (p problemsByLine) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p problemsByLine) Less(i, j int) bool { return p[i].Line < p[j].Line }
+
+func lineAtOffset(content []byte, offset int64) int {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	return strings.Count(string(content[:offset]), "\n") + 1
+}
+
+func lineOfJsonError(content []byte, err error) int {
+	switch typed := err.(type) {
+	case *json.SyntaxError:
+		return lineAtOffset(content, typed.Offset)
+	case *json.UnmarshalTypeError:
+		return lineAtOffset(content, typed.Offset)
+	}
+	return 1
+}
+
+//keyLines has the line of every value in the json content by its path, like `timeout-kill`, `steps[2]` (the line where
+//the second step starts) or `steps[2].command`. The items of lists are numbered from 1 like in the problem messages
+type keyLines map[string]int
+
+//findKeyLines walks the json tokens of the content to find the lines of all the keys and list items, which the decoder
+//of the values does not keep. The content must be valid json
+func findKeyLines(content []byte) keyLines {
+	lines := make(keyLines)
+	decoder := json.NewDecoder(strings.NewReader(string(content)))
+	lines.walkValue(content, decoder, "")
+	return lines
+}
+
+func (k keyLines) walkValue(content []byte, decoder *json.Decoder, path string) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if _, ok := k[path]; !ok && path != "" {
+		k[path] = lineAtOffset(content, decoder.InputOffset())
+	}
+
+	switch token {
+	case json.Delim('{'):
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			keyPath := fmt.Sprintf("%s", keyToken)
+			if path != "" {
+				keyPath = path + "." + keyPath
+			}
+			k[keyPath] = lineAtOffset(content, decoder.InputOffset())
+			if err := k.walkValue(content, decoder, keyPath); err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+	case json.Delim('['):
+		for i := 1; decoder.More(); i++ {
+			if err := k.walkValue(content, decoder, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+	}
+	return err
+}
+
+//line returns the line of the path, or the first line if the path is not in the content
+func (k keyLines) line(path string) int {
+	if line, ok := k[path]; ok {
+		return line
+	}
+	return 1
+}
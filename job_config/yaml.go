@@ -0,0 +1,128 @@
+package job_config
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var yamlErrorLineRegex = regexp.MustCompile(`line (\d+)`)
+
+//isYamlFile is true for job files with the .yaml or .yml extension, all other job files are json
+func isYamlFile(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+//yamlError is a problem of the yaml content with its line
+type yamlError struct {
+	line    int
+	message string
+}
+
+//decodeYaml decodes the yaml content into the same values the json decoder (with UseNumber) gives, so they are validated the
+//same way. The lines of the keys and list items are taken from the yaml nodes, with the same paths as findKeyLines
+func decodeYaml(content []byte) (map[string]interface{}, keyLines, *yamlError) {
+	document := &yaml.Node{}
+	if err := yaml.Unmarshal(content, document); err != nil {
+		line := 1
+		if match := yamlErrorLineRegex.FindStringSubmatch(err.Error()); match != nil {
+			line, _ = strconv.Atoi(match[1])
+		}
+		return nil, nil, &yamlError{line: line, message: err.Error()}
+	}
+
+	lines := make(keyLines)
+	value, yamlErr := lines.yamlValue(document, "")
+	if yamlErr != nil {
+		return nil, nil, yamlErr
+	}
+	raw, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, nil, &yamlError{line: 1, message: "the job file must be a mapping of keys to values"}
+	}
+	return raw, lines, nil
+}
+
+func (k keyLines) yamlValue(node *yaml.Node, path string) (interface{}, *yamlError) {
+	if _, ok := k[path]; !ok && path != "" {
+		k[path] = node.Line
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return k.yamlValue(node.Content[0], path)
+
+	case yaml.AliasNode:
+		return k.yamlValue(node.Alias, path)
+
+	case yaml.MappingNode:
+		m := make(map[string]interface{})
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			if keyNode.Kind != yaml.ScalarNode {
+				return nil, &yamlError{line: keyNode.Line, message: "keys must be strings"}
+			}
+			keyPath := keyNode.Value
+			if path != "" {
+				keyPath = path + "." + keyPath
+			}
+			k[keyPath] = keyNode.Line
+			value, err := k.yamlValue(valueNode, keyPath)
+			if err != nil {
+				return nil, err
+			}
+			m[keyNode.Value] = value
+		}
+		return m, nil
+
+	case yaml.SequenceNode:
+		items := []interface{}{}
+		for i, itemNode := range node.Content {
+			item, err := k.yamlValue(itemNode, fmt.Sprintf("%s[%d]", path, i+1))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+
+	return yamlScalar(node)
+}
+
+//yamlScalar returns the value of the scalar like the json decoder would, so numbers are a json.Number
+func yamlScalar(node *yaml.Node) (interface{}, *yamlError) {
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return nil, &yamlError{line: node.Line, message: err.Error()}
+		}
+		return b, nil
+	case "!!int":
+		var i int64
+		if err := node.Decode(&i); err != nil {
+			return nil, &yamlError{line: node.Line, message: err.Error()}
+		}
+		return json.Number(strconv.FormatInt(i, 10)), nil
+	case "!!float":
+		var f float64
+		if err := node.Decode(&f); err != nil {
+			return nil, &yamlError{line: node.Line, message: err.Error()}
+		}
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+	}
+	//Strings and the other scalars, like timestamps, are kept as they are written
+	return node.Value, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/golang-devops/exec-logger/job_config"
+)
+
+//jobConfig is loaded from the `-config` file before the task is run
+var jobConfig *job_config.Config
+
+func getJobConfigSchema() *job_config.Schema {
+	return &job_config.Schema{
+		FlagSet:       flag.CommandLine,
+		ReservedFlags: []string{"version", "task", "config"},
+		ListSeparators: map[string]string{
+			"parse_patterns":  splitParsePatternString,
+			"redact-patterns": splitParsePatternString,
+			"timeout-sigquit": ",",
+			"redact-env":      ",",
+			"metrics-labels":  ",",
+			"restart-backoff": ",",
+		},
+	}
+}
+
+//loadJobConfig loads the `-config` file and applies its values to the flags that were not given on the command-line
+func loadJobConfig(filePath string) error {
+	config, err := job_config.Load(filePath, getJobConfigSchema())
+	if err != nil {
+		return err
+	}
+	if err = config.ApplyToFlags(flag.CommandLine); err != nil {
+		return err
+	}
+	jobConfig = config
+	return nil
+}
+
+//getJobSteps converts the steps of the job file
+func getJobSteps(config *job_config.Config) (steps []*jobStep) {
+	for _, s := range config.Steps {
+		step := &jobStep{
+			name:            s.Name,
+			runArgs:         s.RunArgs(),
+			workingDir:      s.WorkingDir,
+			timeoutKill:     s.TimeoutKill,
+			continueOnError: s.ContinueOnError,
+			alwaysRun:       s.AlwaysRun,
+		}
+		step.env = (&job_config.Config{Env: s.Env}).EnvList()
+		steps = append(steps, step)
+	}
+	return
+}
+
+//getJobTasks converts the tasks of the job file
+func getJobTasks(config *job_config.Config) (tasks []*jobTask) {
+	for _, t := range config.Tasks {
+		task := &jobTask{
+			name:        t.Name,
+			runArgs:     t.RunArgs(),
+			workingDir:  t.WorkingDir,
+			timeoutKill: t.TimeoutKill,
+			dependsOn:   t.DependsOn,
+		}
+		task.env = (&job_config.Config{Env: t.Env}).EnvList()
+		tasks = append(tasks, task)
+	}
+	return
+}
+
+func doValidateConfigCommand() {
+	if *configFlag == "" {
+		log.Fatal("The -config flag is required for the validate-config task")
+	}
+
+	_, err := job_config.Load(*configFlag, getJobConfigSchema())
+	if validationErr, ok := err.(*job_config.ValidationError); ok {
+		stdioLogger := NewStdioLogger()
+		for _, line := range validationErr.Lines() {
+			stdioLogger.Err("%s", line)
+		}
+		log.Printf("Config file '%s' has %d problems", *configFlag, len(validationErr.Problems))
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Config file '%s' is valid", *configFlag)
+}
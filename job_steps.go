@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+)
+
+//jobStep is a single command of a multi-step job
+type jobStep struct {
+	name            string
+	runArgs         []string
+	env             []string
+	workingDir      string
+	timeoutKill     time.Duration
+	continueOnError bool
+	alwaysRun       bool
+}
+
+//getStepCommandSpec combines the step with the job-wide env, working directory and timeout
+func (c *commandExecer) getStepCommandSpec(step *jobStep) commandSpec {
+	spec := commandSpec{
+		runArgs:             step.runArgs,
+		env:                 append(append([]string{}, c.env...), step.env...),
+		workingDir:          c.workingDir,
+		timeoutKillDuration: c.timeoutKillDuration,
+	}
+	if step.workingDir != "" {
+		spec.workingDir = step.workingDir
+	}
+	if step.timeoutKill > 0 {
+		spec.timeoutKillDuration = step.timeoutKill
+	}
+	return spec
+}
+
+//runSteps runs the steps in order. After a step failed (unless it may continue on error) or the job timeout was reached
+//only the always-run steps still run. The always-run steps are not limited by the job timeout, only by their own
+func (c *commandExecer) runSteps() (exitCode int, returnErr error, statuses []*exec_logger_dtos.StepStatusDto) {
+	var jobDeadline time.Time
+	if c.jobTimeout > 0 {
+		jobDeadline = c.startTime.Add(c.jobTimeout)
+	}
+
+	for i, step := range c.steps {
+		status := &exec_logger_dtos.StepStatusDto{
+			Name:            step.name,
+			ContinueOnError: step.continueOnError,
+			AlwaysRun:       step.alwaysRun,
+		}
+		statuses = append(statuses, status)
+
+		if returnErr != nil && !step.alwaysRun {
+			status.Outcome = exec_logger_dtos.ExitOutcomeSkipped
+			c.stdioHandler.writeFileLine(fmt.Sprintf("===== Skipping step %d/%d '%s' =====", i+1, len(c.steps), step.name))
+			continue
+		}
+
+		spec := c.getStepCommandSpec(step)
+		if !jobDeadline.IsZero() && !step.alwaysRun {
+			remaining := jobDeadline.Sub(time.Now())
+			if remaining <= 0 {
+				status.ExitCode = -1
+				status.Outcome = exec_logger_dtos.ExitOutcomeTimedOut
+				status.Error = fmt.Sprintf("The job timeout of %s was reached before the step started", c.jobTimeout.String())
+				c.stdioHandler.writeErrorLine(fmt.Sprintf("Step '%s': %s", step.name, status.Error))
+				exitCode, returnErr = -1, fmt.Errorf("Step '%s' failed, error: %s", step.name, status.Error)
+				continue
+			}
+			if spec.timeoutKillDuration <= 0 || remaining < spec.timeoutKillDuration {
+				spec.timeoutKillDuration = remaining
+			}
+		}
+
+		c.stdioHandler.writeFileLine(fmt.Sprintf("===== Step %d/%d '%s' started =====", i+1, len(c.steps), step.name))
+		c.stdioHandler.writeFileLine(fmt.Sprintf("Calling commandline: %s", joinCommandLine(spec.runArgs)))
+
+		c.resetAbortOutcome()
+		status.StartTime = time.Now()
+		stepExitCode, stepErr := c.runCommand(spec)
+		if tmpErr := c.statusHandler.FinishProcessLifecycle(time.Now()); tmpErr != nil {
+			c.stdioHandler.writeErrorLine(fmt.Sprintf("Cannot write process lifecycle exit events, error: %s", tmpErr.Error()))
+		}
+		stepDuration := time.Now().Sub(status.StartTime)
+
+		status.ExitCode = stepExitCode
+		status.Duration = stepDuration.String()
+		status.Outcome, status.ResourceLimitExceeded = c.getAbortOutcome()
+		if status.Outcome == "" {
+			if stepErr != nil {
+				status.Outcome = exec_logger_dtos.ExitOutcomeFailed
+			} else {
+				status.Outcome = exec_logger_dtos.ExitOutcomeSuccess
+			}
+		}
+		if stepErr != nil {
+			status.Error = c.redactor.Redact(stepErr.Error())
+		}
+
+		finishedMsg := fmt.Sprintf("===== Step %d/%d '%s' finished with exit code %d (%s) in %s =====", i+1, len(c.steps), step.name, stepExitCode, status.Outcome, stepDuration.String())
+		if stepErr != nil {
+			c.stdioHandler.writeErrorLine(finishedMsg)
+		} else {
+			c.stdioHandler.writeFileLine(finishedMsg)
+		}
+
+		if status.Outcome == exec_logger_dtos.ExitOutcomeAborted {
+			//Otherwise the always-run (cleanup) steps would be aborted right away too
+			c.resetAbortRequest()
+			if err := os.Remove(c.statusHandler.mustAbortFilePath); err != nil && !os.IsNotExist(err) {
+				c.stdioHandler.writeErrorLine(fmt.Sprintf("Cannot remove must-abort file '%s', error: %s", c.statusHandler.mustAbortFilePath, err.Error()))
+			}
+		}
+
+		if stepErr != nil && !step.continueOnError && returnErr == nil {
+			exitCode, returnErr = stepExitCode, fmt.Errorf("Step '%s' failed, error: %s", step.name, stepErr.Error())
+			if exitCode == 0 {
+				exitCode = -1
+			}
+		}
+	}
+
+	return exitCode, returnErr, statuses
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-devops/exec-logger/exec_logger_constants"
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+)
+
+//jobTask is a single command of a job with task dependencies
+type jobTask struct {
+	name        string
+	runArgs     []string
+	env         []string
+	workingDir  string
+	timeoutKill time.Duration
+	dependsOn   []string
+}
+
+func (c *commandExecer) getTaskRunDir(task *jobTask) string {
+	return filepath.Join(c.runDir, exec_logger_constants.TASKS_DIR_NAME, task.name)
+}
+
+//newTaskExecer creates the execer of the task. The task has its own run directory (and files) since tasks run in parallel
+func (c *commandExecer) newTaskExecer(task *jobTask, timeoutKill time.Duration) *commandExecer {
+	options := c.execOptions
+	options.runDir = c.getTaskRunDir(task)
+	options.steps = nil
+	options.tasks = nil
+	options.jobTimeout = 0
+	options.metricsTextfile = ""
+	//Only the job notifies the webhooks, its finished event has the status of every task
+	options.webhooks = nil
+	options.env = append(append([]string{}, c.env...), task.env...)
+	if task.workingDir != "" {
+		options.workingDir = task.workingDir
+	}
+	options.timeoutKillDuration = timeoutKill
+	if options.outputTriggers != nil {
+		options.outputTriggers = options.outputTriggers.Clone()
+	}
+	return NewCommandExecer(c.logger, options, task.runArgs)
+}
+
+type taskResult struct {
+	index    int
+	exitCode int
+	err      error
+}
+
+//runTasks runs every task once all the tasks it depends on succeeded, at most `taskConcurrency` at the same time.
+//The tasks that depend on a failed task are skipped. An abort request aborts the running tasks and skips the others
+func (c *commandExecer) runTasks() (exitCode int, returnErr error, statuses []*exec_logger_dtos.TaskStatusDto) {
+	var jobDeadline time.Time
+	if c.jobTimeout > 0 {
+		jobDeadline = c.startTime.Add(c.jobTimeout)
+	}
+
+	if err := c.statusHandler.WriteLocalContextFile(); err != nil {
+		c.stdioHandler.writeErrorLine(fmt.Sprintf("Cannot write local context, error: %s", err.Error()))
+	}
+
+	indexByName := make(map[string]int)
+	for i, task := range c.tasks {
+		indexByName[task.name] = i
+		statuses = append(statuses, &exec_logger_dtos.TaskStatusDto{
+			Name:      task.name,
+			DependsOn: task.dependsOn,
+			RunDir:    c.getTaskRunDir(task),
+		})
+	}
+
+	finishTask := func(status *exec_logger_dtos.TaskStatusDto, taskExitCode int, taskErr error) {
+		status.ExitTime = time.Now()
+		status.ExitCode = taskExitCode
+		if taskErr != nil {
+			status.Error = c.redactor.Redact(taskErr.Error())
+		}
+		if status.Outcome != exec_logger_dtos.ExitOutcomeSuccess && returnErr == nil {
+			reason := status.Error
+			if reason == "" {
+				reason = "the outcome was " + status.Outcome
+			}
+			exitCode, returnErr = taskExitCode, fmt.Errorf("Task '%s' failed, error: %s", status.Name, reason)
+			if exitCode == 0 {
+				exitCode = -1
+			}
+		}
+	}
+
+	results := make(chan taskResult, len(c.tasks))
+	running := make(map[int]*commandExecer)
+	aborting := false
+	abortRequest := c.getAbortRequest()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	if err := c.statusHandler.WriteAlive(); err != nil {
+		c.stdioHandler.writeErrorLine(fmt.Sprintf("Cannot write alive file, error: %s", err.Error()))
+	}
+
+	for {
+		//Skipping a task can make its dependents skipped too, so loop until nothing changes
+		for changed := true; changed; {
+			changed = false
+			for i, task := range c.tasks {
+				status := statuses[i]
+				if status.Outcome != "" || running[i] != nil {
+					continue
+				}
+
+				ready, failedDependency := true, ""
+				for _, dependency := range task.dependsOn {
+					switch statuses[indexByName[dependency]].Outcome {
+					case "":
+						ready = false
+					case exec_logger_dtos.ExitOutcomeSuccess:
+					default:
+						failedDependency = dependency
+					}
+				}
+				if aborting || failedDependency != "" {
+					status.Outcome = exec_logger_dtos.ExitOutcomeSkipped
+					changed = true
+					reason := "the job was aborted"
+					if failedDependency != "" {
+						reason = fmt.Sprintf("its dependency '%s' did not succeed", failedDependency)
+					}
+					c.stdioHandler.writeFileLine(fmt.Sprintf("===== Skipping task '%s', %s =====", task.name, reason))
+					continue
+				}
+				if !ready || len(running) >= c.taskConcurrency {
+					continue
+				}
+
+				timeoutKill := c.timeoutKillDuration
+				if task.timeoutKill > 0 {
+					timeoutKill = task.timeoutKill
+				}
+				if !jobDeadline.IsZero() {
+					remaining := jobDeadline.Sub(time.Now())
+					if remaining <= 0 {
+						status.Outcome = exec_logger_dtos.ExitOutcomeTimedOut
+						changed = true
+						taskErr := fmt.Errorf("The job timeout of %s was reached before the task started", c.jobTimeout.String())
+						c.stdioHandler.writeErrorLine(fmt.Sprintf("Task '%s': %s", task.name, taskErr.Error()))
+						finishTask(status, -1, taskErr)
+						continue
+					}
+					if timeoutKill <= 0 || remaining < timeoutKill {
+						timeoutKill = remaining
+					}
+				}
+
+				taskExecer := c.newTaskExecer(task, timeoutKill)
+				running[i] = taskExecer
+				status.StartTime = time.Now()
+				c.stdioHandler.writeFileLine(fmt.Sprintf("===== Task '%s' started in '%s': %s =====", task.name, status.RunDir, joinCommandLine(task.runArgs)))
+				go func(index int, taskExecer *commandExecer) {
+					taskExitCode, taskErr := taskExecer.Run()
+					results <- taskResult{index: index, exitCode: taskExitCode, err: taskErr}
+				}(i, taskExecer)
+			}
+		}
+
+		if len(running) == 0 {
+			break
+		}
+
+		select {
+		case result := <-results:
+			status := statuses[result.index]
+			status.Outcome = running[result.index].Outcome()
+			delete(running, result.index)
+			finishTask(status, result.exitCode, result.err)
+			status.Duration = status.ExitTime.Sub(status.StartTime).String()
+
+			finishedMsg := fmt.Sprintf("===== Task '%s' finished with exit code %d (%s) in %s =====", status.Name, status.ExitCode, status.Outcome, status.Duration)
+			if status.Outcome != exec_logger_dtos.ExitOutcomeSuccess {
+				c.stdioHandler.writeErrorLine(finishedMsg)
+			} else {
+				c.stdioHandler.writeFileLine(finishedMsg)
+			}
+			continue
+
+		case <-ticker.C:
+			if err := c.statusHandler.WriteAlive(); err != nil {
+				c.stdioHandler.writeErrorLine(fmt.Sprintf("Cannot write alive file, error: %s", err.Error()))
+			}
+			if mustAbort, checkErr := c.statusHandler.CheckMustAbort(); checkErr != nil {
+				c.stdioHandler.writeFileLine(fmt.Sprintf("Unable to check for abort request, error: %s", checkErr.Error()))
+				continue
+			} else if !mustAbort {
+				continue
+			}
+
+		case <-abortRequest:
+		}
+
+		if !aborting {
+			c.stdioHandler.writeFileLine(fmt.Sprintf("Got ABORT message, aborting the %d running tasks", len(running)))
+			aborting = true
+			abortRequest = nil
+			for _, taskExecer := range running {
+				taskExecer.RequestAbort()
+			}
+		}
+	}
+
+	return exitCode, returnErr, statuses
+}
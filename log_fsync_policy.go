@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+//logFsyncPolicy determines how often the log file is fsync'ed to disk
+type logFsyncPolicy string
+
+const (
+	logFsyncNone logFsyncPolicy = "none"
+	logFsyncLine logFsyncPolicy = "line"
+	logFsyncExit logFsyncPolicy = "exit"
+)
+
+var allLogFsyncPolicies = []logFsyncPolicy{logFsyncNone, logFsyncLine, logFsyncExit}
+
+func getLogFsyncPolicyNamesForFlagHelp() (names []string) {
+	for _, p := range allLogFsyncPolicies {
+		names = append(names, string(p))
+	}
+	return
+}
+
+func parseLogFsyncPolicy(s string) (logFsyncPolicy, error) {
+	for _, p := range allLogFsyncPolicies {
+		if strings.EqualFold(string(p), strings.TrimSpace(s)) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("Unsupported log fsync policy '%s', expected one of: %s", s, strings.Join(getLogFsyncPolicyNamesForFlagHelp(), ", "))
+}
@@ -0,0 +1,111 @@
+package log_patterns
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/golang-devops/exec-logger/job_config"
+)
+
+//Severity of a matched line
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+var allSeverities = []Severity{SeverityError, SeverityWarning, SeverityInfo}
+
+//Rule is a single named pattern. When `Exclude` is true a matching line is treated as normal output, regardless of the rules after it
+type Rule struct {
+	Name     string
+	Pattern  string
+	Severity Severity `json:",omitempty"`
+	Exclude  bool     `json:",omitempty"`
+
+	regex *regexp.Regexp
+}
+
+//MatchString returns true if the (compiled) pattern matches the line
+func (r *Rule) MatchString(line string) bool {
+	return r.regex.MatchString(line)
+}
+
+//RuleSet is an ordered list of rules, the first rule that matches a line wins
+type RuleSet struct {
+	Rules []*Rule
+}
+
+//Add appends a rule, it must still be compiled afterwards
+func (r *RuleSet) Add(rule *Rule) {
+	r.Rules = append(r.Rules, rule)
+}
+
+//Compile validates and compiles all the rules
+func (r *RuleSet) Compile() error {
+	problems := job_config.NewDefinitionProblems("rules")
+	seenNames := make(map[string]bool)
+
+	for i, rule := range r.Rules {
+		if strings.TrimSpace(rule.Name) == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		ruleDesc := fmt.Sprintf("rule %d (%s)", i+1, rule.Name)
+		rulePath := fmt.Sprintf("Rules[%d]", i+1)
+
+		if seenNames[rule.Name] {
+			problems.Add(rulePath+".Name", "%s has a duplicate name", ruleDesc)
+		}
+		seenNames[rule.Name] = true
+
+		if rule.Severity == "" {
+			rule.Severity = SeverityError
+		}
+		if !isValidSeverity(rule.Severity) {
+			problems.Add(rulePath+".Severity", "%s has unknown severity '%s', expected one of: %s", ruleDesc, rule.Severity, strings.Join(SeverityNames(), ", "))
+		}
+
+		if rule.Pattern == "" {
+			problems.Add(rulePath+".Pattern", "%s has an empty pattern", ruleDesc)
+			continue
+		}
+		regex, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			problems.Add(rulePath+".Pattern", "%s has an invalid pattern, error: %s", ruleDesc, err.Error())
+			continue
+		}
+		rule.regex = regex
+	}
+
+	return problems.Err()
+}
+
+//Match returns the first rule matching the line or nil if none matched
+func (r *RuleSet) Match(line string) *Rule {
+	for _, rule := range r.Rules {
+		if rule.MatchString(line) {
+			return rule
+		}
+	}
+	return nil
+}
+
+//SeverityNames returns the names of all the valid severities
+func SeverityNames() (names []string) {
+	for _, s := range allSeverities {
+		names = append(names, string(s))
+	}
+	return
+}
+
+func isValidSeverity(severity Severity) bool {
+	for _, s := range allSeverities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package log_patterns
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/job_config"
+)
+
+func TestRuleSet(t *testing.T) {
+	Convey("Testing RuleSet", t, func() {
+		Convey("Valid rules are matched in order", func() {
+			ruleSet := &RuleSet{}
+			err := job_config.ParseDefinitions("rules.json", []byte(`{
+				"Rules": [
+					{"Name": "retry-noise", "Pattern": "error: retrying", "Exclude": true},
+					{"Name": "compile-error", "Pattern": "error: (.*)"},
+					{"Name": "deprecated", "Pattern": "DEPRECATED", "Severity": "warning"}
+				]
+			}`), ruleSet)
+			So(err, ShouldBeNil)
+
+			So(ruleSet.Match("main.c:1: error: retrying download").Name, ShouldEqual, "retry-noise")
+			So(ruleSet.Match("main.c:1: error: missing ;").Name, ShouldEqual, "compile-error")
+			So(ruleSet.Match("main.c:1: error: missing ;").Severity, ShouldEqual, SeverityError)
+			So(ruleSet.Match("DEPRECATED: use something else").Severity, ShouldEqual, SeverityWarning)
+			So(ruleSet.Match("all good"), ShouldBeNil)
+
+			summary := NewMatchSummary(ruleSet)
+			for _, line := range []string{"error: retrying", "error: x", "error: y", "DEPRECATED", "fine"} {
+				summary.Add(ruleSet.Match(line))
+			}
+			So(summary.CountBySeverity(SeverityError), ShouldEqual, 2)
+			So(summary.CountBySeverity(SeverityWarning), ShouldEqual, 1)
+
+			counts := []int{}
+			for _, c := range summary.Counts() {
+				counts = append(counts, c.Count)
+			}
+			So(counts, ShouldResemble, []int{1, 2, 1})
+		})
+
+		Convey("All validation errors are reported without panicking", func() {
+			err := job_config.ParseDefinitions("rules.json", []byte(`{
+				"Rules": [
+					{"Name": "bad-regex", "Pattern": "error: ("},
+					{"Name": "bad-severity", "Pattern": "x", "Severity": "fatal"},
+					{"Name": "bad-regex", "Pattern": ""}
+				]
+			}`), &RuleSet{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "Invalid config file 'rules.json' with 4 problems:")
+			So(err.Error(), ShouldContainSubstring, "rules.json:3: rule 1 (bad-regex) has an invalid pattern")
+			So(err.Error(), ShouldContainSubstring, "rule 2 (bad-severity) has unknown severity 'fatal'")
+			So(err.Error(), ShouldContainSubstring, "rules.json:5: rule 3 (bad-regex) has a duplicate name")
+			So(err.Error(), ShouldContainSubstring, "rule 3 (bad-regex) has an empty pattern")
+		})
+	})
+}
@@ -0,0 +1,60 @@
+package log_patterns
+
+import "sync"
+
+//NewMatchSummary creates a new MatchSummary for the rules
+func NewMatchSummary(ruleSet *RuleSet) *MatchSummary {
+	return &MatchSummary{
+		ruleSet: ruleSet,
+		counts:  make(map[*Rule]int),
+	}
+}
+
+//MatchSummary counts the matches per rule. It is safe for concurrent use
+type MatchSummary struct {
+	sync.RWMutex
+
+	ruleSet *RuleSet
+	counts  map[*Rule]int
+}
+
+//RuleMatchCount is the number of lines a single rule matched
+type RuleMatchCount struct {
+	Rule  *Rule
+	Count int
+}
+
+//Add counts a match of the rule, nil is ignored
+func (m *MatchSummary) Add(rule *Rule) {
+	if rule == nil {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.counts[rule]++
+}
+
+//CountBySeverity returns the number of matches of the include rules with the severity
+func (m *MatchSummary) CountBySeverity(severity Severity) int {
+	m.RLock()
+	defer m.RUnlock()
+
+	total := 0
+	for rule, count := range m.counts {
+		if !rule.Exclude && rule.Severity == severity {
+			total += count
+		}
+	}
+	return total
+}
+
+//Counts returns the match count of every rule, in the order of the rules
+func (m *MatchSummary) Counts() (counts []*RuleMatchCount) {
+	m.RLock()
+	defer m.RUnlock()
+
+	for _, rule := range m.ruleSet.Rules {
+		counts = append(counts, &RuleMatchCount{Rule: rule, Count: m.counts[rule]})
+	}
+	return
+}
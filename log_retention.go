@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-devops/exec-logger/exec_logger_constants"
+	"github.com/golang-devops/exec-logger/log_segments"
+)
+
+//logRetention determines what happens when the log file reaches the `-max-log-size`
+type logRetention string
+
+const (
+	logRetentionRotate   logRetention = "rotate"
+	logRetentionHeadTail logRetention = "head-tail"
+)
+
+var allLogRetentions = []logRetention{logRetentionRotate, logRetentionHeadTail}
+
+func getLogRetentionNamesForFlagHelp() (names []string) {
+	for _, r := range allLogRetentions {
+		names = append(names, string(r))
+	}
+	return
+}
+
+func parseLogRetention(s string) (logRetention, error) {
+	for _, r := range allLogRetentions {
+		if strings.EqualFold(string(r), strings.TrimSpace(s)) {
+			return r, nil
+		}
+	}
+	return "", fmt.Errorf("Unsupported log retention '%s', expected one of: %s", s, strings.Join(getLogRetentionNamesForFlagHelp(), ", "))
+}
+
+//formatDropMarkerLine is the log line written between the head and tail of a head-tail log
+func formatDropMarkerLine(droppedLines int, droppedBytes int64) string {
+	timestamp := time.Now().Format(exec_logger_constants.LOG_LINE_TIME_FORMAT)
+	return fmt.Sprintf("[%s] ... %d lines (%s) dropped to keep the log within the -max-log-size ...%s", timestamp, droppedLines, formatByteSize(droppedBytes), NEWLINE)
+}
+
+//openLogWriter removes the previous log (with its segments), unless it must be kept, and opens the writer for the size limit and retention of the options
+func (e execOptions) openLogWriter(logFilePath string) (log_segments.Writer, error) {
+	if !e.keepPreviousLog {
+		if err := log_segments.RemoveAll(logFilePath); err != nil {
+			return nil, fmt.Errorf("Failure to remove log file, error: %s", err.Error())
+		}
+	}
+
+	if e.maxLogSize <= 0 {
+		return log_segments.OpenUnbounded(logFilePath)
+	}
+
+	switch e.logRetention {
+	case logRetentionHeadTail:
+		return log_segments.NewHeadTailWriter(logFilePath, e.maxLogSize, formatDropMarkerLine)
+	default:
+		return log_segments.NewRotatingWriter(logFilePath, e.maxLogSize, e.logSegments)
+	}
+}
@@ -0,0 +1,114 @@
+package log_segments
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	rotatedSuffix = ".gz"
+	tailSuffixA   = ".tail-a"
+	tailSuffixB   = ".tail-b"
+	tailSuffixC   = ".tail-c"
+)
+
+var tailSuffixes = []string{tailSuffixA, tailSuffixB, tailSuffixC}
+
+//rotatedFilePath returns the path of the rotated segment, number 1 being the newest
+func rotatedFilePath(logFilePath string, number int) string {
+	return fmt.Sprintf("%s.%d%s", logFilePath, number, rotatedSuffix)
+}
+
+//rotatedSegmentNumbers returns the numbers of the existing rotated segments, from oldest (highest number) to newest
+func rotatedSegmentNumbers(logFilePath string) ([]int, error) {
+	matches, err := filepath.Glob(logFilePath + ".*" + rotatedSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list rotated segments of '%s', error: %s", logFilePath, err.Error())
+	}
+
+	numbers := []int{}
+	for _, match := range matches {
+		numberStr := strings.TrimSuffix(strings.TrimPrefix(match, logFilePath+"."), rotatedSuffix)
+		if number, err := strconv.Atoi(numberStr); err == nil && number > 0 {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(numbers)))
+	return numbers, nil
+}
+
+type tailFileInfo struct {
+	path    string
+	modTime int64
+}
+
+type tailFilesByModTime []tailFileInfo
+
+func (t tailFilesByModTime) Len() int           { return len(t) }
+func (t tailFilesByModTime) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t tailFilesByModTime) Less(i, j int) bool { return t[i].modTime < t[j].modTime }
+
+//existingTailFilePaths returns the tail files of an unfinished head-tail log (for instance when exec-logger itself was killed), oldest first
+func existingTailFilePaths(logFilePath string) []string {
+	tailFiles := []tailFileInfo{}
+	for _, suffix := range tailSuffixes {
+		if info, err := os.Stat(logFilePath + suffix); err == nil {
+			tailFiles = append(tailFiles, tailFileInfo{path: logFilePath + suffix, modTime: info.ModTime().UnixNano()})
+		}
+	}
+	sort.Stable(tailFilesByModTime(tailFiles))
+
+	paths := []string{}
+	for _, t := range tailFiles {
+		paths = append(paths, t.path)
+	}
+	return paths
+}
+
+//SegmentFilePaths returns all the existing files of the log in the order they must be read: the rotated segments (oldest first), the log file itself and lastly the tail files of an unfinished head-tail log
+func SegmentFilePaths(logFilePath string) ([]string, error) {
+	numbers, err := rotatedSegmentNumbers(logFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, number := range numbers {
+		paths = append(paths, rotatedFilePath(logFilePath, number))
+	}
+	if _, err := os.Stat(logFilePath); err == nil {
+		paths = append(paths, logFilePath)
+	}
+	paths = append(paths, existingTailFilePaths(logFilePath)...)
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("Log file '%s' does not exist", logFilePath)
+	}
+	return paths, nil
+}
+
+//RemoveAll removes the log file with all its rotated segments and tail files
+func RemoveAll(logFilePath string) error {
+	numbers, err := rotatedSegmentNumbers(logFilePath)
+	if err != nil {
+		return err
+	}
+
+	paths := []string{logFilePath}
+	for _, suffix := range tailSuffixes {
+		paths = append(paths, logFilePath+suffix)
+	}
+	for _, number := range numbers {
+		paths = append(paths, rotatedFilePath(logFilePath, number))
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("Cannot remove log file '%s', error: %s", path, err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package log_segments
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type segmentsReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (s *segmentsReader) Close() error {
+	var firstErr error
+	for _, c := range s.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+//OpenReader returns a reader of all the segments of the log as if it was a single file, the rotated segments are decompressed
+func OpenReader(logFilePath string) (io.ReadCloser, error) {
+	paths, err := SegmentFilePaths(logFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &segmentsReader{}
+	readers := []io.Reader{}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			reader.Close()
+			return nil, fmt.Errorf("Cannot open log segment '%s', error: %s", path, err.Error())
+		}
+		reader.closers = append(reader.closers, file)
+
+		if !strings.HasSuffix(path, rotatedSuffix) {
+			readers = append(readers, file)
+			continue
+		}
+
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			reader.Close()
+			return nil, fmt.Errorf("Cannot decompress log segment '%s', error: %s", path, err.Error())
+		}
+		reader.closers = append(reader.closers, gzipReader)
+		readers = append(readers, gzipReader)
+	}
+
+	reader.Reader = io.MultiReader(readers...)
+	return reader, nil
+}
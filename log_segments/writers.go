@@ -0,0 +1,356 @@
+package log_segments
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+)
+
+//Writer is the log file writer. Every Write is expected to be one or more whole lines
+type Writer interface {
+	io.Writer
+	Sync() error
+	Close() error
+}
+
+func openLogFile(filePath string) (*os.File, error) {
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0655)
+	if err != nil {
+		return nil, fmt.Errorf("Failure to open log file '%s' for writing, error; %s", filePath, err.Error())
+	}
+	return file, nil
+}
+
+//OpenUnbounded opens the log file without a size limit
+func OpenUnbounded(logFilePath string) (Writer, error) {
+	return openLogFile(logFilePath)
+}
+
+//NewRotatingWriter creates a writer that compresses the log file into a numbered segment once it would exceed `maxSize`.
+//Only the newest `maxSegments` segments are kept
+func NewRotatingWriter(logFilePath string, maxSize int64, maxSegments int) (*RotatingWriter, error) {
+	file, err := openLogFile(logFilePath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Cannot stat log file '%s', error: %s", logFilePath, err.Error())
+	}
+
+	return &RotatingWriter{
+		logFilePath: logFilePath,
+		maxSize:     maxSize,
+		maxSegments: maxSegments,
+		file:        file,
+		size:        info.Size(),
+	}, nil
+}
+
+//RotatingWriter writes the log file and rotates it into gzip compressed segments
+type RotatingWriter struct {
+	logFilePath string
+	maxSize     int64
+	maxSegments int
+
+	file *os.File
+	size int64
+}
+
+func (r *RotatingWriter) Write(p []byte) (int, error) {
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingWriter) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("Cannot close log file '%s' for rotation, error: %s", r.logFilePath, err.Error())
+	}
+
+	if err := r.shiftSegments(); err != nil {
+		return err
+	}
+	if r.maxSegments > 0 {
+		if err := compressFile(r.logFilePath, rotatedFilePath(r.logFilePath, 1)); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(r.logFilePath); err != nil {
+		return fmt.Errorf("Cannot remove rotated log file '%s', error: %s", r.logFilePath, err.Error())
+	}
+
+	file, err := openLogFile(r.logFilePath)
+	if err != nil {
+		return err
+	}
+	r.file = file
+	r.size = 0
+	return nil
+}
+
+//shiftSegments renames segment N to N+1 (newest has number 1) and removes the ones that would exceed `maxSegments`
+func (r *RotatingWriter) shiftSegments() error {
+	numbers, err := rotatedSegmentNumbers(r.logFilePath)
+	if err != nil {
+		return err
+	}
+
+	for _, number := range numbers {
+		path := rotatedFilePath(r.logFilePath, number)
+		if number >= r.maxSegments {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("Cannot remove old log segment '%s', error: %s", path, err.Error())
+			}
+			continue
+		}
+		if err := os.Rename(path, rotatedFilePath(r.logFilePath, number+1)); err != nil {
+			return fmt.Errorf("Cannot rename log segment '%s', error: %s", path, err.Error())
+		}
+	}
+	return nil
+}
+
+func compressFile(srcPath, destPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("Cannot open '%s' to compress, error: %s", srcPath, err.Error())
+	}
+	defer src.Close()
+
+	tempPath := destPath + ".tmp"
+	dest, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0655)
+	if err != nil {
+		return fmt.Errorf("Cannot create compressed log segment '%s', error: %s", tempPath, err.Error())
+	}
+	defer os.Remove(tempPath)
+
+	gzipWriter := gzip.NewWriter(dest)
+	if _, err = io.Copy(gzipWriter, src); err == nil {
+		err = gzipWriter.Close()
+	}
+	if err == nil {
+		err = dest.Sync()
+	}
+	if closeErr := dest.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot compress '%s' to '%s', error: %s", srcPath, tempPath, err.Error())
+	}
+
+	if err = os.Rename(tempPath, destPath); err != nil {
+		return fmt.Errorf("Cannot rename '%s' to '%s', error: %s", tempPath, destPath, err.Error())
+	}
+	return nil
+}
+
+func (r *RotatingWriter) Sync() error {
+	return r.file.Sync()
+}
+
+func (r *RotatingWriter) Close() error {
+	return r.file.Close()
+}
+
+//DropMarkerFunc returns the line written between the head and tail, describing what was dropped
+type DropMarkerFunc func(droppedLines int, droppedBytes int64) string
+
+//NewHeadTailWriter creates a writer that keeps the first and last `maxSize/2` bytes of the log. The tail is written to three rotating
+//files (so it survives a crash) which are appended to the log file on Close, after the line of `dropMarker`
+func NewHeadTailWriter(logFilePath string, maxSize int64, dropMarker DropMarkerFunc) (*HeadTailWriter, error) {
+	file, err := openLogFile(logFilePath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Cannot stat log file '%s', error: %s", logFilePath, err.Error())
+	}
+
+	return &HeadTailWriter{
+		logFilePath: logFilePath,
+		headLimit:   maxSize / 2,
+		tailLimit:   maxSize - maxSize/2,
+		dropMarker:  dropMarker,
+		head:        file,
+		headSize:    info.Size(),
+	}, nil
+}
+
+type tailFile struct {
+	path  string
+	file  *os.File
+	size  int64
+	lines int
+}
+
+//HeadTailWriter keeps the head and tail of the log and counts the lines and bytes dropped in between
+type HeadTailWriter struct {
+	logFilePath string
+	headLimit   int64
+	tailLimit   int64
+	dropMarker  DropMarkerFunc
+
+	head     *os.File
+	headSize int64
+
+	inTail       bool
+	tails        [3]*tailFile //Oldest first, the current tail file is the last one
+	droppedLines int
+	droppedBytes int64
+}
+
+func (h *HeadTailWriter) Write(p []byte) (int, error) {
+	if !h.inTail && h.headSize+int64(len(p)) <= h.headLimit {
+		n, err := h.head.Write(p)
+		h.headSize += int64(n)
+		return n, err
+	}
+
+	if !h.inTail {
+		if err := h.startTail(); err != nil {
+			return 0, err
+		}
+	}
+
+	//Each tail file holds half of the tail, so the two older ones always hold at least the whole tail even right after a
+	//rotation. The extra bytes are dropped on Close
+	current := h.tails[len(h.tails)-1]
+	if current.size > 0 && current.size+int64(len(p)) > h.tailLimit/2 {
+		if err := h.rotateTails(); err != nil {
+			return 0, err
+		}
+		current = h.tails[len(h.tails)-1]
+	}
+
+	n, err := current.file.Write(p)
+	current.size += int64(n)
+	current.lines += bytes.Count(p[:n], []byte("\n"))
+	return n, err
+}
+
+func (h *HeadTailWriter) startTail() error {
+	h.inTail = true
+	for i, suffix := range tailSuffixes {
+		path := h.logFilePath + suffix
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0655)
+		if err != nil {
+			return fmt.Errorf("Cannot create log tail file '%s', error: %s", path, err.Error())
+		}
+		h.tails[i] = &tailFile{path: path, file: file}
+	}
+	return nil
+}
+
+//rotateTails drops the content of the oldest tail file and makes it the current one
+func (h *HeadTailWriter) rotateTails() error {
+	oldest := h.tails[0]
+	if err := oldest.file.Truncate(0); err != nil {
+		return fmt.Errorf("Cannot truncate log tail file '%s', error: %s", oldest.path, err.Error())
+	}
+	if _, err := oldest.file.Seek(0, os.SEEK_SET); err != nil {
+		return fmt.Errorf("Cannot seek log tail file '%s', error: %s", oldest.path, err.Error())
+	}
+	h.droppedLines += oldest.lines
+	h.droppedBytes += oldest.size
+	oldest.size = 0
+	oldest.lines = 0
+
+	copy(h.tails[:], h.tails[1:])
+	h.tails[len(h.tails)-1] = oldest
+	return nil
+}
+
+//skipTailExcess counts the whole lines at the start of the oldest tail file that do not fit in the tail anymore, as dropped.
+//It returns the offset in the oldest file where the kept tail starts
+func (h *HeadTailWriter) skipTailExcess() (int64, error) {
+	excess := -h.tailLimit
+	for _, tail := range h.tails {
+		excess += tail.size
+	}
+	oldest := h.tails[0]
+	if excess <= 0 {
+		return 0, nil
+	}
+	if excess >= oldest.size {
+		excess = oldest.size
+	}
+
+	if _, err := oldest.file.Seek(0, os.SEEK_SET); err != nil {
+		return 0, fmt.Errorf("Cannot seek log tail file '%s', error: %s", oldest.path, err.Error())
+	}
+	reader := bufio.NewReader(oldest.file)
+	offset := int64(0)
+	lines := 0
+	for offset < excess {
+		line, err := reader.ReadBytes('\n')
+		offset += int64(len(line))
+		if err != nil {
+			break
+		}
+		lines++
+	}
+	h.droppedLines += lines
+	h.droppedBytes += offset
+	return offset, nil
+}
+
+func (h *HeadTailWriter) Sync() error {
+	if h.inTail {
+		return h.tails[len(h.tails)-1].file.Sync()
+	}
+	return h.head.Sync()
+}
+
+//Close appends the drop marker and the tail files to the log file
+func (h *HeadTailWriter) Close() error {
+	if !h.inTail {
+		return h.head.Close()
+	}
+
+	oldestOffset, err := h.skipTailExcess()
+	if err != nil {
+		return err
+	}
+	if h.droppedLines > 0 {
+		if _, err := io.WriteString(h.head, h.dropMarker(h.droppedLines, h.droppedBytes)); err != nil {
+			return fmt.Errorf("Cannot write drop marker to log file '%s', error: %s", h.logFilePath, err.Error())
+		}
+	}
+
+	for i, tail := range h.tails {
+		offset := int64(0)
+		if i == 0 {
+			offset = oldestOffset
+		}
+		if _, err := tail.file.Seek(offset, os.SEEK_SET); err != nil {
+			return fmt.Errorf("Cannot seek log tail file '%s', error: %s", tail.path, err.Error())
+		}
+		if _, err := io.Copy(h.head, tail.file); err != nil {
+			return fmt.Errorf("Cannot append log tail file '%s' to the log file, error: %s", tail.path, err.Error())
+		}
+	}
+	if err := h.head.Sync(); err != nil {
+		return fmt.Errorf("Cannot fsync log file '%s', error: %s", h.logFilePath, err.Error())
+	}
+
+	for _, tail := range h.tails {
+		tail.file.Close()
+		if err := os.Remove(tail.path); err != nil {
+			return fmt.Errorf("Cannot remove log tail file '%s', error: %s", tail.path, err.Error())
+		}
+	}
+	return h.head.Close()
+}
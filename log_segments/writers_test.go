@@ -0,0 +1,81 @@
+package log_segments
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func writeLines(w Writer, from, to int) {
+	for i := from; i <= to; i++ {
+		//Every line is exactly 10 bytes
+		if _, err := w.Write([]byte(fmt.Sprintf("line %04d\n", i))); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func readAll(logFilePath string) string {
+	reader, err := OpenReader(logFilePath)
+	So(err, ShouldBeNil)
+	defer reader.Close()
+	content, err := ioutil.ReadAll(reader)
+	So(err, ShouldBeNil)
+	return string(content)
+}
+
+func TestWriters(t *testing.T) {
+	Convey("Testing the size bounded log writers", t, func() {
+		tempDir, err := ioutil.TempDir("", "log-segments-test")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tempDir)
+		logFilePath := filepath.Join(tempDir, "log.log")
+
+		Convey("RotatingWriter keeps the newest segments and they are read as one log", func() {
+			w, err := NewRotatingWriter(logFilePath, 100, 2)
+			So(err, ShouldBeNil)
+			writeLines(w, 1, 35)
+			So(w.Close(), ShouldBeNil)
+
+			paths, err := SegmentFilePaths(logFilePath)
+			So(err, ShouldBeNil)
+			So(paths, ShouldResemble, []string{logFilePath + ".2.gz", logFilePath + ".1.gz", logFilePath})
+
+			lines := strings.Split(strings.TrimSpace(readAll(logFilePath)), "\n")
+			So(len(lines), ShouldEqual, 25)
+			So(lines[0], ShouldEqual, "line 0011")
+			So(lines[24], ShouldEqual, "line 0035")
+
+			So(RemoveAll(logFilePath), ShouldBeNil)
+			_, err = SegmentFilePaths(logFilePath)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("HeadTailWriter keeps the head and tail with the drop marker in between", func() {
+			w, err := NewHeadTailWriter(logFilePath, 100, func(droppedLines int, droppedBytes int64) string {
+				return fmt.Sprintf("dropped %d lines %d bytes\n", droppedLines, droppedBytes)
+			})
+			So(err, ShouldBeNil)
+			writeLines(w, 1, 31)
+
+			//Before Close the tail is still in the separate files but must be readable
+			So(readAll(logFilePath), ShouldContainSubstring, "line 0031")
+
+			So(w.Close(), ShouldBeNil)
+			lines := strings.Split(strings.TrimSpace(readAll(logFilePath)), "\n")
+			So(lines[:5], ShouldResemble, []string{"line 0001", "line 0002", "line 0003", "line 0004", "line 0005"})
+			//Each of the three tail files holds up to 25 bytes (2 lines), the first line of the oldest one does not fit in the 50 bytes of the tail
+			So(lines[5], ShouldEqual, "dropped 21 lines 210 bytes")
+			So(lines[6:], ShouldResemble, []string{"line 0027", "line 0028", "line 0029", "line 0030", "line 0031"})
+
+			paths, err := SegmentFilePaths(logFilePath)
+			So(err, ShouldBeNil)
+			So(paths, ShouldResemble, []string{logFilePath})
+		})
+	})
+}
@@ -5,8 +5,19 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"regexp"
+	"runtime"
 	"strings"
+	"time"
+
+	"github.com/go-zero-boilerplate/loggers"
+
+	"github.com/golang-devops/exec-logger/job_config"
+	"github.com/golang-devops/exec-logger/log_patterns"
+	"github.com/golang-devops/exec-logger/output_actions"
+	"github.com/golang-devops/exec-logger/output_sinks"
+	"github.com/golang-devops/exec-logger/redaction"
+	"github.com/golang-devops/exec-logger/syslog_sink"
+	"github.com/golang-devops/exec-logger/webhooks"
 )
 
 var (
@@ -16,10 +27,53 @@ var (
 var (
 	versionFlag             = flag.Bool("version", false, "Print the version and exit")
 	taskFlag                = flag.String("task", "", "The task to run ("+strings.Join(getTaskNamesForFlagHelp(), ", ")+")")
+	configFlag              = flag.String("config", "", "A json (or .yaml/.yml) job file with the command, env, working-dir and the values of flags. Flags given on the command-line override it")
 	stdErrIsError           = flag.Bool("stderr-is-error", false, "If any stderr line is printed we will exit with non-zero exit code")
 	timeoutKillDuration     = flag.Duration("timeout-kill", 0, "The timeout after which to auto-kill the running process")
-	parseErrorPatternsFlag  = flag.String("parse_patterns", "", `Additional error patterns. Split multiple with `+splitParsePatternString+`, for example (without quotes). 'ERROR: (.*)'`+splitParsePatternString+`'MYERROR: (.*)'`)
+	jobTimeoutFlag          = flag.Duration("job-timeout", 0, "The timeout of all the steps (or tasks) of a job together, the always-run steps are only limited by their own timeout")
+	timeoutDiagnosticsFlag  = flag.Bool("timeout-diagnostics", false, "Capture a diagnostics.json snapshot of the process tree before killing it on timeout")
+	timeoutSigquitFlag      = flag.String("timeout-sigquit", "", "Comma separated process names to send SIGQUIT to before killing on timeout (for Go/Java thread dumps)")
+	timeoutSigquitWaitFlag  = flag.Duration("timeout-sigquit-wait", 5*time.Second, "How long to wait for the thread dumps after sending SIGQUIT")
+	parseErrorPatternsFlag  = flag.String("parse_patterns", "", `Additional error patterns (also applied to the output lines with the exec task). Split multiple with `+splitParsePatternString+`, for example (without quotes). 'ERROR: (.*)'`+splitParsePatternString+`'MYERROR: (.*)'`)
+	patternsFileFlag        = flag.String("patterns-file", "", "A json file with named pattern rules, each with a Pattern, Severity ("+strings.Join(log_patterns.SeverityNames(), ", ")+") and optionally Exclude. With the exec task they classify the output lines")
+	parseContextFlag        = flag.Int("context", 0, "Number of lines to print before and after each error line with the parselog task (used with -errors-only)")
+	parseSinceFlag          = flag.String("since", "", "Only parse the log lines from this local time on, for example '2016-05-07 10:00:00'")
+	parseUntilFlag          = flag.String("until", "", "Only parse the log lines up to this local time, for example '2016-05-07 11:00:00'")
+	parseErrorsOnlyFlag     = flag.Bool("errors-only", false, "Only print the error lines (and their -context lines) with the parselog task")
+	actionsFileFlag         = flag.String("actions-file", "", "A json file with output actions, each with a Pattern, optional Count and Within duration and the Action ("+strings.Join(output_actions.ActionTypeNames(), ", ")+")")
+	redactPatternsFlag      = flag.String("redact-patterns", "", `Regex patterns of secrets to replace with `+redaction.Marker+` before writing to disk. Split multiple with `+splitParsePatternString+`. With capture groups only the groups are replaced, for example 'password=(\S+)'`)
+	redactEnvFlag           = flag.String("redact-env", "", "Comma separated names of environment variables (wildcards like *_TOKEN allowed) of which the values must be redacted")
+	redactFileFlag          = flag.String("redact-file", "", "A file with one secret value per line that must be redacted")
 	recordResourceUsageFlag = flag.Bool("record-resource-usage", false, "Record resource usage - CPU, RAM, etc")
+	recordIOMetricsFlag     = flag.Bool("record-io-metrics", false, "Also record disk I/O, open file descriptors, sockets and threads per process (implies -record-resource-usage)")
+	recordProcessesFlag     = flag.Bool("record-processes", false, "Record spawn/exit events of all processes in the tree to the processes.jsonl file")
+	maxMemoryFlag           = flag.String("max-memory", "", "Kill the process when the memory of its whole process tree exceeds this size, for example 512MB or 2GB")
+	maxCPUTimeFlag          = flag.Duration("max-cpu-time", 0, "Kill the process when the CPU time of its whole process tree exceeds this duration")
+	maxProcessesFlag        = flag.Int("max-processes", 0, "Kill the process when its process tree has more than this number of processes")
+	reportFormatFlag        = flag.String("report-format", "text", "The output format of the usage-report task ("+strings.Join(getUsageReportFormatNamesForFlagHelp(), ", ")+")")
+	reportFileFlag          = flag.String("report-file", "", "The file to write the usage-report to, by default it is written to stdout")
+	metricsTextfileFlag     = flag.String("metrics-textfile", "", "Path of a prometheus textfile (for the node_exporter textfile collector) to write the live metrics to")
+	metricsLabelsFlag       = flag.String("metrics-labels", "", "Labels added to every metric in the -metrics-textfile, for example job=nightly,branch=master")
+	maxLogSizeFlag          = flag.String("max-log-size", "", "Limit the size of the log file, for example 100MB. What happens at the limit depends on -log-retention")
+	logRetentionFlag        = flag.String("log-retention", string(logRetentionRotate), "How to keep the log within the -max-log-size ("+strings.Join(getLogRetentionNamesForFlagHelp(), ", ")+")")
+	logSegmentsFlag         = flag.Int("log-segments", 5, "The number of gzip compressed segments to keep with the rotate -log-retention")
+	batchFileFlag           = flag.String("batch-file", "", "The file with one shell command per line for the batch task, by default they are read from stdin")
+	batchDirFlag            = flag.String("batch-dir", "exec-logger-batch", "The directory of the batch task, every command gets its own numbered run directory in it")
+	concurrencyFlag         = flag.Int("concurrency", runtime.NumCPU(), "The maximum number of commands of the batch task, jobs of the spool task (or tasks of a job) that run at the same time")
+	failFastFlag            = flag.Bool("fail-fast", false, "Abort the running commands and skip the remaining ones of the batch task as soon as one fails")
+	restartFlag             = flag.String("restart", string(restartOnFailure), "When the supervise task restarts the command ("+strings.Join(getRestartPolicyNamesForFlagHelp(), ", ")+")")
+	maxRestartsFlag         = flag.Int("max-restarts", 5, "The supervise task gives up when the command has to be restarted more than this number of times within the -restart-window, 0 means no limit")
+	restartWindowFlag       = flag.Duration("restart-window", 10*time.Minute, "The window of the -max-restarts, the restart backoff also starts over after the command ran for this long")
+	restartBackoffFlag      = flag.String("restart-backoff", "1s,5s,30s,1m", "Comma separated delays before each next restart of the supervise task, the last one is repeated")
+	scheduleFileFlag        = flag.String("schedule-file", "", "A json file with the schedules of the schedule task, each with a Name, Cron expression or Every interval and the Command or Shell")
+	scheduleDirFlag         = flag.String("schedule-dir", "exec-logger-schedule", "The directory of the schedule task with the state file, every schedule gets its own run directory in it")
+	spoolDirFlag            = flag.String("spool-dir", "", "The directory the spool task watches for json job files, they are moved to its running, done and failed subdirectories")
+	webhooksFileFlag        = flag.String("webhooks-file", "", "A json file with webhooks, each with a URL and the Events ("+strings.Join(webhooks.EventNames(), ", ")+") to POST a json payload to")
+	sinksFileFlag           = flag.String("sinks-file", "", "A json file with sinks that also get every log line, each with a Type ("+strings.Join(output_sinks.SinkTypeNames(), ", ")+") and optionally the Streams ("+strings.Join(output_sinks.StreamNames(), ", ")+")")
+	syslogFlag              = flag.String("syslog", "", "Also send every log line to syslog (RFC 5424), for example udp://host:514, tcp://host:601 or unix:///dev/log")
+	syslogAppNameFlag       = flag.String("syslog-app-name", "exec-logger", "The app-name (tag) of the -syslog messages")
+	syslogFacilityFlag      = flag.String("syslog-facility", "user", "The facility of the -syslog messages ("+strings.Join(syslog_sink.FacilityNames(), ", ")+")")
+	logFsyncFlag            = flag.String("log-fsync", string(logFsyncNone), "When to fsync the log file ("+strings.Join(getLogFsyncPolicyNamesForFlagHelp(), ", ")+")")
 )
 
 var (
@@ -31,6 +85,12 @@ var (
 	}{
 		{Name: "exec", Handler: doExecCommand},
 		{Name: "parselog", Handler: doParseLogToStdioCommand},
+		{Name: "usage-report", Handler: doUsageReportCommand},
+		{Name: "validate-config", Handler: doValidateConfigCommand},
+		{Name: "batch", Handler: doBatchCommand},
+		{Name: "supervise", Handler: doSuperviseCommand},
+		{Name: "schedule", Handler: doScheduleCommand},
+		{Name: "spool", Handler: doSpoolCommand},
 	}
 )
 
@@ -41,16 +101,43 @@ func getTaskNamesForFlagHelp() (names []string) {
 	return
 }
 
+//buildJobExecOptions builds the options of the command (or steps or tasks) given after the flags or in the `-config` file
+func buildJobExecOptions(stdioLogger loggers.LoggerStdIO) (execOptions, []string) {
+	args := flag.Args()
+	if len(args) == 0 && jobConfig != nil {
+		args = jobConfig.RunArgs()
+	}
+	var steps []*jobStep
+	var tasks []*jobTask
+	if len(flag.Args()) == 0 && jobConfig != nil {
+		steps = getJobSteps(jobConfig)
+		tasks = getJobTasks(jobConfig)
+	}
+	if len(args) == 0 && len(steps) == 0 && len(tasks) == 0 {
+		log.Fatal("No command to run, give it after the flags or in the -config file")
+	}
+
+	options := buildExecOptions(stdioLogger)
+	options.steps = steps
+	options.tasks = tasks
+	options.jobTimeout = *jobTimeoutFlag
+	if options.taskConcurrency = *concurrencyFlag; len(tasks) > 0 && options.taskConcurrency < 1 {
+		log.Fatalf("The concurrency must be at least 1, got %d", options.taskConcurrency)
+	}
+	return options, args
+}
+
 func doExecCommand() {
 	stdioLogger := NewStdioLogger()
-	args := flag.Args()
-	execer := NewCommandExecer(stdioLogger, *stdErrIsError, *timeoutKillDuration, *recordResourceUsageFlag, args)
+	options, args := buildJobExecOptions(stdioLogger)
+
+	execer := NewCommandExecer(stdioLogger, options, args)
 	exitCode, err := execer.Run()
 
 	fmt.Printf("exit code was %d\n", exitCode)
 
 	if err != nil {
-		log.Printf("Error running command: %s.\nThe arguments used were: %s", err.Error(), fmt.Sprintf("%+v", args))
+		log.Printf("Error running command: %s.\nThe arguments used were: %s", options.redactor.Redact(err.Error()), options.redactor.Redact(fmt.Sprintf("%+v", args)))
 		if exitCode != -1 {
 			os.Exit(exitCode)
 		} else {
@@ -61,21 +148,241 @@ func doExecCommand() {
 	os.Exit(0)
 }
 
+//buildExecOptions builds the options of the exec (and batch) task from the flags and the `-config` file, it exits on invalid flags
+func buildExecOptions(stdioLogger loggers.LoggerStdIO) execOptions {
+	logFsync, err := parseLogFsyncPolicy(*logFsyncFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	options := execOptions{
+		stdErrIsError:       *stdErrIsError,
+		timeoutKillDuration: *timeoutKillDuration,
+		recordResourceUsage: *recordResourceUsageFlag || *recordIOMetricsFlag,
+		recordIOMetrics:     *recordIOMetricsFlag,
+		recordProcesses:     *recordProcessesFlag,
+		resourceLimits: resourceLimits{
+			maxCPUTime:   *maxCPUTimeFlag,
+			maxProcesses: *maxProcessesFlag,
+		},
+		logFsync:        logFsync,
+		metricsTextfile: *metricsTextfileFlag,
+
+		timeoutDiagnostics:    *timeoutDiagnosticsFlag,
+		timeoutQuitSignalWait: *timeoutSigquitWaitFlag,
+	}
+	if jobConfig != nil {
+		options.env = jobConfig.EnvList()
+		options.workingDir = jobConfig.WorkingDir
+	}
+	for _, name := range strings.Split(*timeoutSigquitFlag, ",") {
+		if strings.TrimSpace(name) != "" {
+			options.timeoutQuitSignalNames = append(options.timeoutQuitSignalNames, strings.TrimSpace(name))
+		}
+	}
+	if *maxMemoryFlag != "" {
+		maxMemoryBytes, err := parseByteSize(*maxMemoryFlag)
+		if err != nil {
+			log.Fatalf("Invalid -max-memory, error: %s", err.Error())
+		}
+		options.resourceLimits.maxMemoryKB = int(maxMemoryBytes / 1024)
+	}
+
+	if options.outputRules, err = buildOutputRuleSet(stdioLogger, *patternsFileFlag, *parseErrorPatternsFlag); err != nil {
+		log.Fatal(err)
+	}
+
+	if options.redactor, err = buildRedactor(stdioLogger, *redactPatternsFlag, *redactEnvFlag, *redactFileFlag, append(os.Environ(), options.env...)); err != nil {
+		log.Fatal(err)
+	}
+
+	if *actionsFileFlag != "" {
+		options.outputTriggers = &output_actions.TriggerSet{}
+		if err = job_config.LoadDefinitions(*actionsFileFlag, options.outputTriggers); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *webhooksFileFlag != "" {
+		options.webhooks = &webhooks.Notifier{}
+		if err = job_config.LoadDefinitions(*webhooksFileFlag, options.webhooks); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	options.sinks = &output_sinks.Definitions{}
+	if *sinksFileFlag != "" {
+		if err = job_config.LoadDefinitions(*sinksFileFlag, options.sinks); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *syslogFlag != "" {
+		//The -syslog flags are a shortcut for a syslog sink
+		syslogSink := &output_sinks.Definition{
+			Name:     "syslog",
+			Type:     output_sinks.SinkTypeSyslog,
+			Address:  *syslogFlag,
+			AppName:  *syslogAppNameFlag,
+			Facility: *syslogFacilityFlag,
+		}
+		options.sinks.Sinks = append(options.sinks.Sinks, syslogSink)
+		if err = options.sinks.Compile(); err != nil {
+			log.Fatalf("Invalid -syslog flags, error: %s", err.Error())
+		}
+	}
+
+	if *maxLogSizeFlag != "" {
+		if options.maxLogSize, err = parseByteSize(*maxLogSizeFlag); err != nil {
+			log.Fatalf("Invalid -max-log-size, error: %s", err.Error())
+		}
+	}
+	if options.logRetention, err = parseLogRetention(*logRetentionFlag); err != nil {
+		log.Fatal(err)
+	}
+	if options.logSegments = *logSegmentsFlag; options.logSegments < 0 {
+		log.Fatalf("The -log-segments flag cannot be negative, got %d", options.logSegments)
+	}
+
+	if options.metricsLabels, err = parseMetricLabels(*metricsLabelsFlag); err != nil {
+		log.Fatalf("Invalid -metrics-labels, error: %s", err.Error())
+	}
+
+	return options
+}
+
+func doBatchCommand() {
+	stdioLogger := NewStdioLogger()
+
+	options := buildExecOptions(stdioLogger)
+	if options.metricsTextfile != "" {
+		log.Fatal("The -metrics-textfile flag is not supported with the batch task, since all commands would write the same file")
+	}
+
+	summary, err := handleBatchCommand(stdioLogger, options, *batchFileFlag, *batchDirFlag, *concurrencyFlag, *failFastFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Batch finished: %d succeeded, %d failed, %d skipped of %d commands", summary.Succeeded, summary.Failed, summary.Skipped, summary.Total)
+	if summary.Succeeded != summary.Total {
+		os.Exit(1)
+	}
+}
+
+func doSuperviseCommand() {
+	stdioLogger := NewStdioLogger()
+	options, args := buildJobExecOptions(stdioLogger)
+	if options.maxLogSize > 0 && options.logRetention == logRetentionHeadTail {
+		log.Fatal("The head-tail -log-retention is not supported with the supervise task, since all restarts append to the same log")
+	}
+
+	policy, err := parseRestartPolicy(*restartFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	backoff, err := parseDurationList(*restartBackoffFlag)
+	if err != nil {
+		log.Fatalf("Invalid -restart-backoff, error: %s", err.Error())
+	}
+	if *maxRestartsFlag < 0 {
+		log.Fatalf("The -max-restarts flag cannot be negative, got %d", *maxRestartsFlag)
+	}
+
+	s := &supervisor{
+		logger:        stdioLogger,
+		options:       options,
+		runArgs:       args,
+		policy:        policy,
+		maxRestarts:   *maxRestartsFlag,
+		restartWindow: *restartWindowFlag,
+		backoff:       backoff,
+	}
+	exitCode, err := s.run()
+
+	fmt.Printf("exit code was %d\n", exitCode)
+	if err != nil {
+		log.Printf("Error running command: %s", options.redactor.Redact(err.Error()))
+		if exitCode != -1 && exitCode != 0 {
+			os.Exit(exitCode)
+		}
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+func doScheduleCommand() {
+	if *scheduleFileFlag == "" {
+		log.Fatal("The -schedule-file flag is required for the schedule task")
+	}
+	stdioLogger := NewStdioLogger()
+
+	options := buildExecOptions(stdioLogger)
+	if options.metricsTextfile != "" {
+		log.Fatal("The -metrics-textfile flag is not supported with the schedule task, since all schedules would write the same file")
+	}
+
+	if err := handleScheduleCommand(stdioLogger, options, *scheduleFileFlag, *scheduleDirFlag); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func doSpoolCommand() {
+	if *spoolDirFlag == "" {
+		log.Fatal("The -spool-dir flag is required for the spool task")
+	}
+	stdioLogger := NewStdioLogger()
+
+	options := buildExecOptions(stdioLogger)
+	if options.metricsTextfile != "" {
+		log.Fatal("The -metrics-textfile flag is not supported with the spool task, since all jobs would write the same file")
+	}
+	options.taskConcurrency = *concurrencyFlag
+
+	if err := handleSpoolCommand(stdioLogger, options, *spoolDirFlag, *concurrencyFlag); err != nil {
+		log.Fatal(err)
+	}
+}
+
 func doParseLogToStdioCommand() {
 	stdioLogger := NewStdioLogger()
 
-	additionalErrorPatterns = []*regexp.Regexp{}
-	if len(*parseErrorPatternsFlag) > 0 {
-		for _, s := range strings.Split(*parseErrorPatternsFlag, splitParsePatternString) {
-			if strings.TrimSpace(s) == "" {
-				continue
-			}
-			additionalErrorPatterns = append(additionalErrorPatterns, regexp.MustCompile(s))
-			stdioLogger.Out("Additional error pattern added: %s", s)
+	ruleSet, err := buildParseLogRuleSet(stdioLogger, *patternsFileFlag, *parseErrorPatternsFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	options := parseLogOptions{
+		ruleSet:      ruleSet,
+		contextLines: *parseContextFlag,
+		errorsOnly:   *parseErrorsOnlyFlag,
+	}
+	if options.contextLines < 0 {
+		log.Fatalf("The -context flag cannot be negative, got %d", options.contextLines)
+	}
+	if *parseSinceFlag != "" {
+		if options.since, err = parseLogTime(*parseSinceFlag); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *parseUntilFlag != "" {
+		if options.until, err = parseLogTime(*parseUntilFlag); err != nil {
+			log.Fatal(err)
 		}
 	}
 
-	err := handleParseLogToStdioCommand(stdioLogger)
+	errorMatchCount, err := handleParseLogToStdioCommand(stdioLogger, options)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if errorMatchCount > 0 {
+		log.Printf("Found %d error lines", errorMatchCount)
+		os.Exit(1)
+	}
+}
+
+func doUsageReportCommand() {
+	err := handleUsageReportCommand(*reportFormatFlag, *reportFileFlag)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -96,6 +403,12 @@ func main() {
 		os.Exit(2)
 	}
 
+	if *configFlag != "" && !strings.EqualFold(*taskFlag, "validate-config") {
+		if err := loadJobConfig(*configFlag); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	var handler func() = nil
 	for _, t := range tasks {
 		if strings.EqualFold(t.Name, *taskFlag) {
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+)
+
+var metricLabelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+//reservedMetricLabelNames are added by the writer itself, the node_exporter rejects the whole textfile with duplicate label names
+var reservedMetricLabelNames = []string{"stream", "outcome"}
+
+type metricLabel struct {
+	Name  string
+	Value string
+}
+
+//parseMetricLabels parses labels in the format "job=nightly,branch=master". Duplicate and reserved names are not allowed
+func parseMetricLabels(s string) ([]metricLabel, error) {
+	labels := []metricLabel{}
+	seenNames := make(map[string]bool)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		nameAndValue := strings.SplitN(pair, "=", 2)
+		if len(nameAndValue) != 2 {
+			return nil, fmt.Errorf("Invalid metric label '%s', expected format name=value", pair)
+		}
+		name := strings.TrimSpace(nameAndValue[0])
+		if !metricLabelNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("Invalid metric label name '%s', must match %s", name, metricLabelNamePattern.String())
+		}
+		if strings.HasPrefix(name, "__") {
+			return nil, fmt.Errorf("Invalid metric label name '%s', names starting with __ are reserved", name)
+		}
+		for _, reserved := range reservedMetricLabelNames {
+			if name == reserved {
+				return nil, fmt.Errorf("Invalid metric label name '%s', it is added by exec-logger itself (reserved are: %s)", name, strings.Join(reservedMetricLabelNames, ", "))
+			}
+		}
+		if seenNames[name] {
+			return nil, fmt.Errorf("Duplicate metric label name '%s'", name)
+		}
+		seenNames[name] = true
+		labels = append(labels, metricLabel{Name: name, Value: nameAndValue[1]})
+	}
+	return labels, nil
+}
+
+//runMetrics are the values written to the metrics textfile. The Sample and Summary are nil if no resource usage was sampled (yet)
+type runMetrics struct {
+	StartTime   time.Time
+	Elapsed     time.Duration
+	Sample      *exec_logger_dtos.ResourceUsageDto
+	Summary     *exec_logger_dtos.ResourceSummaryDto
+	StdoutLines int
+	StderrLines int
+
+	Exited   bool
+	ExitCode int
+	Outcome  string
+}
+
+//metricsTextfileWriter writes the metrics in the prometheus text format, to be picked up by the node_exporter textfile collector
+type metricsTextfileWriter struct {
+	sync.Mutex
+
+	filePath   string
+	labels     []metricLabel
+	exited     bool
+	lastSample *exec_logger_dtos.ResourceUsageDto
+}
+
+func escapeMetricLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func (m *metricsTextfileWriter) formatLabels(extraLabels ...metricLabel) string {
+	all := append(append([]metricLabel{}, m.labels...), extraLabels...)
+	if len(all) == 0 {
+		return ""
+	}
+	parts := []string{}
+	for _, l := range all {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, l.Name, escapeMetricLabelValue(l.Value)))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func (m *metricsTextfileWriter) format(metrics runMetrics) []byte {
+	var buf bytes.Buffer
+
+	writeGauge := func(name, help string, value float64, extraLabels ...metricLabel) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n", name)
+		fmt.Fprintf(&buf, "%s%s %s\n", name, m.formatLabels(extraLabels...), strconv.FormatFloat(value, 'f', -1, 64))
+	}
+
+	running := 1.0
+	if metrics.Exited {
+		running = 0
+	}
+	writeGauge("exec_logger_running", "Whether the command is still running.", running)
+	writeGauge("exec_logger_start_time_seconds", "Unix time when the command started.", float64(metrics.StartTime.Unix()))
+	writeGauge("exec_logger_elapsed_seconds", "Seconds since the command started.", metrics.Elapsed.Seconds())
+
+	fmt.Fprintf(&buf, "# HELP exec_logger_output_lines Number of lines the command wrote per stream.\n")
+	fmt.Fprintf(&buf, "# TYPE exec_logger_output_lines gauge\n")
+	fmt.Fprintf(&buf, "exec_logger_output_lines%s %d\n", m.formatLabels(metricLabel{Name: "stream", Value: "stdout"}), metrics.StdoutLines)
+	fmt.Fprintf(&buf, "exec_logger_output_lines%s %d\n", m.formatLabels(metricLabel{Name: "stream", Value: "stderr"}), metrics.StderrLines)
+
+	if metrics.Sample != nil {
+		summed := metrics.Sample.GetSummedProcessesResourceUsage()
+		writeGauge("exec_logger_tree_memory_bytes", "Memory of the whole process tree in the last sample.", float64(summed.MemoryKB)*1024)
+		writeGauge("exec_logger_tree_process_count", "Number of processes in the tree in the last sample.", float64(len(metrics.Sample.ProcessesResourceUsage)))
+	}
+	if metrics.Summary != nil {
+		writeGauge("exec_logger_tree_cpu_seconds", "CPU seconds used by the whole process tree so far.", float64(metrics.Summary.TotalCPUSeconds))
+		writeGauge("exec_logger_tree_peak_memory_bytes", "Peak memory of the whole process tree so far.", float64(metrics.Summary.PeakMemoryKB)*1024)
+	}
+
+	if metrics.Exited {
+		writeGauge("exec_logger_exit_code", "Exit code of the command.", float64(metrics.ExitCode))
+		writeGauge("exec_logger_outcome", "The outcome of the command, the value is always 1.", 1, metricLabel{Name: "outcome", Value: metrics.Outcome})
+	}
+
+	return buf.Bytes()
+}
+
+//write atomically replaces the textfile. Once the exited metrics were written, writes of a still running command are ignored.
+//If the metrics do not have a Sample the previous one is used again
+func (m *metricsTextfileWriter) write(metrics runMetrics) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.exited && !metrics.Exited {
+		return nil
+	}
+	m.exited = metrics.Exited
+
+	if metrics.Sample == nil {
+		metrics.Sample = m.lastSample
+	} else {
+		m.lastSample = metrics.Sample
+	}
+
+	return writeFileAtomic(m.filePath, m.format(metrics), 0644)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+)
+
+func TestMetricsTextfile(t *testing.T) {
+	Convey("Testing parseMetricLabels", t, func() {
+		labels, err := parseMetricLabels(`job=nightly, branch=feature/x="y"`)
+		So(err, ShouldBeNil)
+		So(labels, ShouldResemble, []metricLabel{
+			metricLabel{Name: "job", Value: "nightly"},
+			metricLabel{Name: "branch", Value: `feature/x="y"`},
+		})
+
+		_, err = parseMetricLabels("job")
+		So(err, ShouldNotBeNil)
+		_, err = parseMetricLabels("1job=x")
+		So(err, ShouldNotBeNil)
+
+		_, err = parseMetricLabels("job=a,branch=b,job=c")
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "Duplicate metric label name 'job'")
+		for _, reserved := range []string{"stream=x", "outcome=x", "__name__=x"} {
+			_, err = parseMetricLabels(reserved)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "reserved")
+		}
+	})
+
+	Convey("Testing metricsTextfileWriter", t, func() {
+		tmpDir, err := ioutil.TempDir("", "exec-logger-metrics")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tmpDir)
+
+		writer := &metricsTextfileWriter{
+			filePath: filepath.Join(tmpDir, "exec-logger.prom"),
+			labels:   []metricLabel{metricLabel{Name: "branch", Value: `feature/x="y"`}},
+		}
+
+		sample := &exec_logger_dtos.ResourceUsageDto{
+			ProcessesResourceUsage: []*exec_logger_dtos.ProcessResourceUsage{
+				&exec_logger_dtos.ProcessResourceUsage{Pid: 1, MemoryKB: 1024},
+				&exec_logger_dtos.ProcessResourceUsage{Pid: 2, MemoryKB: 1024},
+			},
+		}
+		So(writer.write(runMetrics{StartTime: time.Unix(1462600000, 0), Elapsed: 1500 * time.Millisecond, Sample: sample, StdoutLines: 3}), ShouldBeNil)
+
+		content, err := ioutil.ReadFile(writer.filePath)
+		So(err, ShouldBeNil)
+		So(string(content), ShouldContainSubstring, "# TYPE exec_logger_running gauge\nexec_logger_running{branch=\"feature/x=\\\"y\\\"\"} 1\n")
+		So(string(content), ShouldContainSubstring, "exec_logger_start_time_seconds{branch=\"feature/x=\\\"y\\\"\"} 1462600000\n")
+		So(string(content), ShouldContainSubstring, "exec_logger_elapsed_seconds{branch=\"feature/x=\\\"y\\\"\"} 1.5\n")
+		So(string(content), ShouldContainSubstring, "exec_logger_output_lines{branch=\"feature/x=\\\"y\\\"\",stream=\"stdout\"} 3\n")
+		So(string(content), ShouldContainSubstring, "exec_logger_tree_memory_bytes{branch=\"feature/x=\\\"y\\\"\"} 2097152\n")
+		So(string(content), ShouldContainSubstring, "exec_logger_tree_process_count{branch=\"feature/x=\\\"y\\\"\"} 2\n")
+		So(string(content), ShouldNotContainSubstring, "exec_logger_exit_code")
+
+		Convey("The exited metrics are final and keep the last sample", func() {
+			So(writer.write(runMetrics{Exited: true, ExitCode: 2, Outcome: exec_logger_dtos.ExitOutcomeFailed}), ShouldBeNil)
+			So(writer.write(runMetrics{Sample: &exec_logger_dtos.ResourceUsageDto{}}), ShouldBeNil)
+
+			content, err := ioutil.ReadFile(writer.filePath)
+			So(err, ShouldBeNil)
+			So(string(content), ShouldContainSubstring, "exec_logger_running{branch=\"feature/x=\\\"y\\\"\"} 0\n")
+			So(string(content), ShouldContainSubstring, "exec_logger_exit_code{branch=\"feature/x=\\\"y\\\"\"} 2\n")
+			So(string(content), ShouldContainSubstring, "exec_logger_outcome{branch=\"feature/x=\\\"y\\\"\",outcome=\"failed\"} 1\n")
+			So(string(content), ShouldContainSubstring, "exec_logger_tree_memory_bytes{branch=\"feature/x=\\\"y\\\"\"} 2097152\n")
+		})
+	})
+}
@@ -0,0 +1,36 @@
+package output_actions
+
+import (
+	"sort"
+	"strings"
+	"syscall"
+)
+
+//signalsByName are the signals that can be sent with the signal action, extended per OS
+var signalsByName = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGABRT": syscall.SIGABRT,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+//LookupSignal returns the signal by its name, with or without the SIG prefix
+func LookupSignal(name string) (syscall.Signal, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig, ok := signalsByName[name]
+	return sig, ok
+}
+
+//SignalNames returns the sorted names of the supported signals
+func SignalNames() (names []string) {
+	for name := range signalsByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
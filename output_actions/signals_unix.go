@@ -0,0 +1,10 @@
+// +build !windows
+
+package output_actions
+
+import "syscall"
+
+func init() {
+	signalsByName["SIGUSR1"] = syscall.SIGUSR1
+	signalsByName["SIGUSR2"] = syscall.SIGUSR2
+}
@@ -0,0 +1,175 @@
+package output_actions
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/golang-devops/exec-logger/job_config"
+)
+
+//ActionType is what to do when a trigger fires
+type ActionType string
+
+const (
+	ActionAbort    ActionType = "abort"
+	ActionSignal   ActionType = "signal"
+	ActionSnapshot ActionType = "snapshot"
+	ActionFail     ActionType = "fail"
+	ActionMarker   ActionType = "marker"
+)
+
+var allActionTypes = []ActionType{ActionAbort, ActionSignal, ActionSnapshot, ActionFail, ActionMarker}
+
+//Trigger fires its Action when an output line matches the Pattern `Count` times within the `Within` duration.
+//A zero Count means once and an empty Within means the matches may be any time apart
+type Trigger struct {
+	Name    string
+	Pattern string
+	Count   int    `json:",omitempty"`
+	Within  string `json:",omitempty"`
+	Action  ActionType
+	Signal  string `json:",omitempty"`
+	Message string `json:",omitempty"`
+	//Processes are the names of the processes in the tree that get the Signal, all processes of the tree if empty
+	Processes []string `json:",omitempty"`
+
+	regex      *regexp.Regexp
+	within     time.Duration
+	matchTimes []time.Time
+	fireCount  int
+}
+
+//FireCount returns how many times the trigger fired so far
+func (t *Trigger) FireCount() int {
+	return t.fireCount
+}
+
+//TriggerSet is the list of triggers evaluated for every output line. It is safe for concurrent use
+type TriggerSet struct {
+	sync.Mutex
+
+	Triggers []*Trigger
+}
+
+//Compile validates and compiles all the triggers
+func (t *TriggerSet) Compile() error {
+	problems := job_config.NewDefinitionProblems("actions")
+	seenNames := make(map[string]bool)
+
+	for i, trigger := range t.Triggers {
+		if strings.TrimSpace(trigger.Name) == "" {
+			trigger.Name = fmt.Sprintf("action-%d", i+1)
+		}
+		triggerDesc := fmt.Sprintf("action %d (%s)", i+1, trigger.Name)
+		triggerPath := fmt.Sprintf("Triggers[%d]", i+1)
+
+		if seenNames[trigger.Name] {
+			problems.Add(triggerPath+".Name", "%s has a duplicate name", triggerDesc)
+		}
+		seenNames[trigger.Name] = true
+
+		if !isValidActionType(trigger.Action) {
+			problems.Add(triggerPath+".Action", "%s has unknown action '%s', expected one of: %s", triggerDesc, trigger.Action, strings.Join(ActionTypeNames(), ", "))
+		}
+		if trigger.Action == ActionSignal {
+			if sig, ok := LookupSignal(trigger.Signal); !ok {
+				problems.Add(triggerPath+".Signal", "%s has unknown signal '%s', expected one of: %s", triggerDesc, trigger.Signal, strings.Join(SignalNames(), ", "))
+			} else if runtime.GOOS == "windows" && sig != syscall.SIGKILL {
+				problems.Add(triggerPath+".Signal", "%s has signal '%s' but only SIGKILL can be sent on windows", triggerDesc, trigger.Signal)
+			}
+		}
+
+		if trigger.Count < 0 {
+			problems.Add(triggerPath+".Count", "%s has a negative count %d", triggerDesc, trigger.Count)
+		}
+		if trigger.Count == 0 {
+			trigger.Count = 1
+		}
+
+		if trigger.Within != "" {
+			within, err := time.ParseDuration(trigger.Within)
+			if err != nil || within <= 0 {
+				problems.Add(triggerPath+".Within", "%s has an invalid within duration '%s'", triggerDesc, trigger.Within)
+			}
+			trigger.within = within
+		}
+
+		if trigger.Pattern == "" {
+			problems.Add(triggerPath+".Pattern", "%s has an empty pattern", triggerDesc)
+			continue
+		}
+		regex, err := regexp.Compile(trigger.Pattern)
+		if err != nil {
+			problems.Add(triggerPath+".Pattern", "%s has an invalid pattern, error: %s", triggerDesc, err.Error())
+			continue
+		}
+		trigger.regex = regex
+	}
+
+	return problems.Err()
+}
+
+//Evaluate returns the triggers that fire because of this line. After firing a trigger needs `Count` new matches to fire again
+func (t *TriggerSet) Evaluate(line string, now time.Time) (fired []*Trigger) {
+	t.Lock()
+	defer t.Unlock()
+
+	for _, trigger := range t.Triggers {
+		if !trigger.regex.MatchString(line) {
+			continue
+		}
+
+		trigger.matchTimes = append(trigger.matchTimes, now)
+		if trigger.within > 0 {
+			oldest := 0
+			for oldest < len(trigger.matchTimes) && now.Sub(trigger.matchTimes[oldest]) > trigger.within {
+				oldest++
+			}
+			trigger.matchTimes = trigger.matchTimes[oldest:]
+		}
+
+		if len(trigger.matchTimes) >= trigger.Count {
+			trigger.matchTimes = nil
+			trigger.fireCount++
+			fired = append(fired, trigger)
+		}
+	}
+	return
+}
+
+//Clone returns a copy of the compiled triggers without their match state, to evaluate the output of another command
+func (t *TriggerSet) Clone() *TriggerSet {
+	t.Lock()
+	defer t.Unlock()
+
+	clone := &TriggerSet{}
+	for _, trigger := range t.Triggers {
+		triggerCopy := *trigger
+		triggerCopy.matchTimes = nil
+		triggerCopy.fireCount = 0
+		clone.Triggers = append(clone.Triggers, &triggerCopy)
+	}
+	return clone
+}
+
+//ActionTypeNames returns the names of all the valid actions
+func ActionTypeNames() (names []string) {
+	for _, a := range allActionTypes {
+		names = append(names, string(a))
+	}
+	return
+}
+
+func isValidActionType(action ActionType) bool {
+	for _, a := range allActionTypes {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
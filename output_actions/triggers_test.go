@@ -0,0 +1,69 @@
+package output_actions
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/job_config"
+)
+
+func TestTriggerSet(t *testing.T) {
+	Convey("Testing TriggerSet", t, func() {
+		Convey("Triggers fire after Count matches within the duration", func() {
+			triggerSet := &TriggerSet{}
+			err := job_config.ParseDefinitions("actions.json", []byte(`{
+				"Triggers": [
+					{"Name": "oom", "Pattern": "OutOfMemory", "Action": "abort"},
+					{"Name": "flaky", "Pattern": "connection reset", "Count": 3, "Within": "1m", "Action": "fail"},
+					{"Name": "dump", "Pattern": "stuck", "Action": "signal", "Signal": "quit"}
+				]
+			}`), triggerSet)
+			So(err, ShouldBeNil)
+
+			t0 := time.Date(2016, 5, 7, 10, 0, 0, 0, time.UTC)
+			names := func(fired []*Trigger) (names []string) {
+				for _, trigger := range fired {
+					names = append(names, trigger.Name)
+				}
+				return
+			}
+
+			So(names(triggerSet.Evaluate("java.lang.OutOfMemory", t0)), ShouldResemble, []string{"oom"})
+			So(triggerSet.Evaluate("all fine", t0), ShouldBeEmpty)
+
+			So(triggerSet.Evaluate("connection reset", t0), ShouldBeEmpty)
+			So(triggerSet.Evaluate("connection reset", t0.Add(30*time.Second)), ShouldBeEmpty)
+			//The first match is now more than a minute ago
+			So(triggerSet.Evaluate("connection reset", t0.Add(61*time.Second)), ShouldBeEmpty)
+			So(names(triggerSet.Evaluate("connection reset", t0.Add(62*time.Second))), ShouldResemble, []string{"flaky"})
+			//Needs another 3 matches to fire again
+			So(triggerSet.Evaluate("connection reset", t0.Add(63*time.Second)), ShouldBeEmpty)
+
+			So(triggerSet.Triggers[1].FireCount(), ShouldEqual, 1)
+
+			clone := triggerSet.Clone()
+			So(clone.Triggers[1].FireCount(), ShouldEqual, 0)
+			So(names(clone.Evaluate("java.lang.OutOfMemory", t0)), ShouldResemble, []string{"oom"})
+			So(triggerSet.Triggers[0].FireCount(), ShouldEqual, 1)
+		})
+
+		Convey("All validation errors are reported", func() {
+			err := job_config.ParseDefinitions("actions.json", []byte(`{
+				"Triggers": [
+					{"Name": "a", "Pattern": "(", "Action": "abort"},
+					{"Name": "b", "Pattern": "x", "Action": "reboot"},
+					{"Name": "c", "Pattern": "x", "Action": "signal", "Signal": "SIGNOPE"},
+					{"Name": "d", "Pattern": "x", "Action": "fail", "Within": "soon"}
+				]
+			}`), &TriggerSet{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "Invalid config file 'actions.json' with 4 problems:")
+			So(err.Error(), ShouldContainSubstring, "actions.json:3: action 1 (a) has an invalid pattern")
+			So(err.Error(), ShouldContainSubstring, "action 2 (b) has unknown action 'reboot'")
+			So(err.Error(), ShouldContainSubstring, "action 3 (c) has unknown signal 'SIGNOPE'")
+			So(err.Error(), ShouldContainSubstring, "action 4 (d) has an invalid within duration 'soon'")
+		})
+	})
+}
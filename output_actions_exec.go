@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/golang-devops/exec-logger/diagnostics"
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/output_actions"
+)
+
+func (c *commandExecer) addFailedOutputAction(name string) {
+	c.abortMutex.Lock()
+	defer c.abortMutex.Unlock()
+	c.failedOutputActions = append(c.failedOutputActions, name)
+}
+
+func (c *commandExecer) getFailedOutputActions() []string {
+	c.abortMutex.Lock()
+	defer c.abortMutex.Unlock()
+	return append([]string{}, c.failedOutputActions...)
+}
+
+//runOutputAction is called (from the output scanning goroutines) for every trigger that fired on an output line
+func (c *commandExecer) runOutputAction(cmd *exec.Cmd, trigger *output_actions.Trigger, line string) {
+	c.stdioHandler.writeFileLine(fmt.Sprintf("Output action '%s' (%s) triggered by line: %s", trigger.Name, trigger.Action, line))
+
+	switch trigger.Action {
+	case output_actions.ActionAbort:
+		if outcome, _ := c.getAbortOutcome(); outcome != "" {
+			return
+		}
+		c.stdioHandler.writeFileLine(fmt.Sprintf("Output action '%s' requested an abort, now aborting", trigger.Name))
+		c.setAbortOutcome(exec_logger_dtos.ExitOutcomeAborted, nil)
+		c.abortProcess(cmd)
+
+	case output_actions.ActionSignal:
+		sig, _ := output_actions.LookupSignal(trigger.Signal)
+		signaled, warnings := diagnostics.SendSignal(cmd.Process.Pid, sig, trigger.Signal, trigger.Processes)
+		for _, warning := range warnings {
+			c.stdioHandler.writeErrorLine(warning)
+		}
+		for _, p := range signaled {
+			c.stdioHandler.writeFileLine(fmt.Sprintf("Sent %s to pid %d (%s)", trigger.Signal, p.Pid, p.Name))
+		}
+		if len(signaled) == 0 && len(warnings) == 0 {
+			c.stdioHandler.writeFileLine(fmt.Sprintf("Output action '%s' found no processes to send %s to", trigger.Name, trigger.Signal))
+		}
+
+	case output_actions.ActionSnapshot:
+		dto := diagnostics.Capture(cmd.Process.Pid, fmt.Sprintf("Output action '%s'", trigger.Name))
+		if err := c.statusHandler.AppendSnapshot(dto); err != nil {
+			c.stdioHandler.writeErrorLine(fmt.Sprintf("Cannot write snapshot, error: %s", err.Error()))
+			return
+		}
+		c.stdioHandler.writeFileLine(fmt.Sprintf("Appended snapshot of %d processes to '%s'", len(dto.Processes), c.statusHandler.snapshotsFilePath))
+
+	case output_actions.ActionFail:
+		c.addFailedOutputAction(trigger.Name)
+
+	case output_actions.ActionMarker:
+		message := trigger.Message
+		if message == "" {
+			message = trigger.Name
+		}
+		c.stdioHandler.writeFileLine("EASY_EXEC_MARKER: " + message)
+	}
+}
@@ -0,0 +1,167 @@
+package output_sinks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang-devops/exec-logger/job_config"
+	"github.com/golang-devops/exec-logger/syslog_sink"
+)
+
+//SinkType is the kind of destination of a sink
+type SinkType string
+
+const (
+	//SinkTypeFile writes the lines like in the log file to the Path
+	SinkTypeFile SinkType = "file"
+	//SinkTypeConsole writes the lines like in the log file to stdout (and the lines of the stderr stream to stderr)
+	SinkTypeConsole SinkType = "console"
+	//SinkTypeJsonLines writes the lines and lifecycle events as json lines to the Path
+	SinkTypeJsonLines SinkType = "json-lines"
+	//SinkTypeProcess starts the Command and writes the json lines to its stdin
+	SinkTypeProcess SinkType = "process"
+	//SinkTypeSyslog sends the lines to the syslog Address
+	SinkTypeSyslog SinkType = "syslog"
+)
+
+var allSinkTypes = []SinkType{SinkTypeFile, SinkTypeConsole, SinkTypeJsonLines, SinkTypeProcess, SinkTypeSyslog}
+
+const (
+	defaultBufferSize     = 10000
+	defaultSyslogAppName  = "exec-logger"
+	defaultSyslogFacility = "user"
+)
+
+//Definition is a sink of the sinks file. Every sink has its own buffer of BufferSize events (default 10000)
+type Definition struct {
+	Name       string
+	Type       SinkType
+	Streams    []Stream `json:",omitempty"`
+	BufferSize int      `json:",omitempty"`
+
+	//Path is the file of the file and json-lines sinks, lines are appended to it
+	Path string `json:",omitempty"`
+	//Command is the program and arguments of the process sink
+	Command []string `json:",omitempty"`
+	//Address, AppName and Facility are for the syslog sink, the Address is like udp://host:514, tcp://host:601 or unix:///dev/log
+	Address  string `json:",omitempty"`
+	AppName  string `json:",omitempty"`
+	Facility string `json:",omitempty"`
+
+	syslogConfig syslog_sink.Config
+}
+
+//Definitions are the sinks that get every line of the log, next to the log file itself
+type Definitions struct {
+	Sinks []*Definition
+}
+
+//Compile validates and compiles all the sinks
+func (d *Definitions) Compile() error {
+	problems := job_config.NewDefinitionProblems("sinks")
+	seenNames := make(map[string]bool)
+
+	for i, sink := range d.Sinks {
+		if strings.TrimSpace(sink.Name) == "" {
+			sink.Name = fmt.Sprintf("%s-%d", sink.Type, i+1)
+		}
+		sinkDesc := fmt.Sprintf("sink %d (%s)", i+1, sink.Name)
+		sinkPath := fmt.Sprintf("Sinks[%d]", i+1)
+
+		if seenNames[sink.Name] {
+			problems.Add(sinkPath+".Name", "%s has a duplicate name", sinkDesc)
+		}
+		seenNames[sink.Name] = true
+
+		for _, stream := range sink.Streams {
+			if !isValidStream(stream) {
+				problems.Add(sinkPath+".Streams", "%s has unknown stream '%s', expected one of: %s", sinkDesc, stream, strings.Join(StreamNames(), ", "))
+			}
+		}
+		if sink.BufferSize < 0 {
+			problems.Add(sinkPath+".BufferSize", "%s has a negative buffer size %d", sinkDesc, sink.BufferSize)
+		} else if sink.BufferSize == 0 {
+			sink.BufferSize = defaultBufferSize
+		}
+
+		switch sink.Type {
+		case SinkTypeFile, SinkTypeJsonLines:
+			if strings.TrimSpace(sink.Path) == "" {
+				problems.Add(sinkPath+".Path", "%s requires a Path", sinkDesc)
+			}
+		case SinkTypeConsole:
+		case SinkTypeProcess:
+			if len(sink.Command) == 0 || strings.TrimSpace(sink.Command[0]) == "" {
+				problems.Add(sinkPath+".Command", "%s requires a Command", sinkDesc)
+			}
+		case SinkTypeSyslog:
+			if err := sink.compileSyslog(); err != nil {
+				problems.Add(sinkPath, "%s %s", sinkDesc, err.Error())
+			}
+		default:
+			problems.Add(sinkPath+".Type", "%s has unknown type '%s', expected one of: %s", sinkDesc, sink.Type, strings.Join(SinkTypeNames(), ", "))
+		}
+	}
+
+	return problems.Err()
+}
+
+func (d *Definition) compileSyslog() error {
+	network, address, err := syslog_sink.ParseAddress(d.Address)
+	if err != nil {
+		return fmt.Errorf("has an invalid Address, error: %s", err.Error())
+	}
+	if d.AppName == "" {
+		d.AppName = defaultSyslogAppName
+	}
+	if d.Facility == "" {
+		d.Facility = defaultSyslogFacility
+	}
+	facility, ok := syslog_sink.LookupFacility(d.Facility)
+	if !ok {
+		return fmt.Errorf("has unknown facility '%s', expected one of: %s", d.Facility, strings.Join(syslog_sink.FacilityNames(), ", "))
+	}
+	d.syslogConfig = syslog_sink.Config{Network: network, Address: address, AppName: d.AppName, Facility: facility}
+	return nil
+}
+
+//Open creates the sink, the definition must be compiled
+func (d *Definition) Open() (Sink, error) {
+	switch d.Type {
+	case SinkTypeFile:
+		return openFileSink(d.Path)
+	case SinkTypeConsole:
+		return newConsoleSink(), nil
+	case SinkTypeJsonLines:
+		return openJsonLinesSink(d.Path)
+	case SinkTypeProcess:
+		return startProcessSink(d.Command)
+	case SinkTypeSyslog:
+		return &syslogSink{sink: syslog_sink.New(d.syslogConfig)}, nil
+	}
+	return nil, fmt.Errorf("Unknown sink type '%s'", d.Type)
+}
+
+//AddTo opens every sink and adds it to the FanOut with its buffer. Sinks that can not be opened are skipped,
+//`onOpenError` is called for each of them
+func (d *Definitions) AddTo(fanOut *FanOut, onOpenError func(sinkName string, err error)) {
+	if d == nil {
+		return
+	}
+	for _, definition := range d.Sinks {
+		sink, err := definition.Open()
+		if err != nil {
+			onOpenError(definition.Name, err)
+			continue
+		}
+		fanOut.Add(definition.Name, sink, definition.BufferSize, definition.Streams)
+	}
+}
+
+//SinkTypeNames returns the names of all the sink types
+func SinkTypeNames() (names []string) {
+	for _, sinkType := range allSinkTypes {
+		names = append(names, string(sinkType))
+	}
+	return
+}
@@ -0,0 +1,226 @@
+package output_sinks
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+)
+
+//FanOut writes every event to all of its sinks. A sink added without a buffer is written to directly, so the writer waits for it.
+//A sink with a buffer is written to in the background and the events are dropped when its buffer is full, so it never blocks the writer
+type FanOut struct {
+	sync.Mutex
+
+	outputs []*output
+	//onError is called for every failed write to a sink without a buffer
+	onError func(sinkName string, err error)
+}
+
+type output struct {
+	name    string
+	sink    Sink
+	streams []Stream
+	buffer  *buffer
+}
+
+//Report is the number of events a buffered sink dropped, because its buffer was full or writing failed, and the last error
+type Report struct {
+	SinkName string
+	Dropped  int
+	LastErr  error
+}
+
+//NewFanOut creates an empty FanOut, `onError` is called for every failed write to a sink without a buffer
+func NewFanOut(onError func(sinkName string, err error)) *FanOut {
+	return &FanOut{onError: onError}
+}
+
+//Add adds the sink, with a buffer of `bufferSize` events if it is larger than 0. The sink only gets the lines of the
+//`streams`, or all lines if empty. It gets all the lifecycle events
+func (f *FanOut) Add(name string, sink Sink, bufferSize int, streams []Stream) {
+	f.Lock()
+	defer f.Unlock()
+
+	o := &output{name: name, sink: sink, streams: streams}
+	if bufferSize > 0 {
+		o.buffer = newBuffer(sink, bufferSize)
+	}
+	f.outputs = append(f.outputs, o)
+}
+
+func (o *output) wants(stream Stream) bool {
+	if len(o.streams) == 0 {
+		return true
+	}
+	for _, s := range o.streams {
+		if s == stream {
+			return true
+		}
+	}
+	return false
+}
+
+//WriteLine writes the line to every sink that wants its stream
+func (f *FanOut) WriteLine(event *LineEvent) {
+	f.Lock()
+	defer f.Unlock()
+
+	for _, o := range f.outputs {
+		if !o.wants(event.Stream) {
+			continue
+		}
+		if o.buffer != nil {
+			o.buffer.queue(event)
+		} else if err := o.sink.WriteLine(event); err != nil {
+			f.onError(o.name, err)
+		}
+	}
+}
+
+//WriteLifecycleEvent writes the lifecycle event to every sink
+func (f *FanOut) WriteLifecycleEvent(event *exec_logger_dtos.LifecycleEventDto) {
+	f.Lock()
+	defer f.Unlock()
+
+	for _, o := range f.outputs {
+		if o.buffer != nil {
+			o.buffer.queue(event)
+		} else if err := o.sink.WriteLifecycleEvent(event); err != nil {
+			f.onError(o.name, err)
+		}
+	}
+}
+
+//Flush writes the buffered events and closes the sinks with a buffer, waiting at most `timeout` for all of them together.
+//These sinks are removed, so the events written afterwards only go to the sinks without a buffer.
+//Returns a report for every sink that dropped events or failed
+func (f *FanOut) Flush(timeout time.Duration) (reports []*Report) {
+	f.Lock()
+	defer f.Unlock()
+
+	buffered := []*output{}
+	remaining := []*output{}
+	for _, o := range f.outputs {
+		if o.buffer != nil {
+			close(o.buffer.events)
+			buffered = append(buffered, o)
+		} else {
+			remaining = append(remaining, o)
+		}
+	}
+	f.outputs = remaining
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	timedOut := false
+	for _, o := range buffered {
+		if !timedOut {
+			select {
+			case <-o.buffer.finished:
+			case <-timer.C:
+				timedOut = true
+			}
+		}
+
+		report := o.buffer.report(timeout)
+		if report.Dropped > 0 || report.LastErr != nil {
+			report.SinkName = o.name
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
+
+//Close closes the sinks without a buffer, Flush must be called first
+func (f *FanOut) Close() {
+	f.Lock()
+	defer f.Unlock()
+
+	for _, o := range f.outputs {
+		if err := o.sink.Close(); err != nil {
+			f.onError(o.name, err)
+		}
+	}
+	f.outputs = nil
+}
+
+//buffer writes the queued events to the sink in the background
+type buffer struct {
+	sink     Sink
+	events   chan interface{}
+	finished chan struct{}
+
+	sync.Mutex
+	dropped int
+	lastErr error
+}
+
+func newBuffer(sink Sink, size int) *buffer {
+	b := &buffer{
+		sink:     sink,
+		events:   make(chan interface{}, size),
+		finished: make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+//queue never blocks, the event is dropped when the buffer is full
+func (b *buffer) queue(event interface{}) {
+	select {
+	case b.events <- event:
+	default:
+		b.drop(nil)
+	}
+}
+
+func (b *buffer) drop(err error) {
+	b.Lock()
+	defer b.Unlock()
+	b.dropped++
+	if err != nil {
+		b.lastErr = err
+	}
+}
+
+//report returns the dropped events so far, including the ones that are still queued if the sink did not finish
+func (b *buffer) report(timeout time.Duration) *Report {
+	select {
+	case <-b.finished:
+		b.Lock()
+		defer b.Unlock()
+		return &Report{Dropped: b.dropped, LastErr: b.lastErr}
+	default:
+		b.Lock()
+		defer b.Unlock()
+		return &Report{
+			Dropped: b.dropped + len(b.events),
+			LastErr: fmt.Errorf("Timeout of %s writing the remaining events", timeout.String()),
+		}
+	}
+}
+
+func (b *buffer) run() {
+	defer close(b.finished)
+
+	for event := range b.events {
+		var err error
+		switch e := event.(type) {
+		case *LineEvent:
+			err = b.sink.WriteLine(e)
+		case *exec_logger_dtos.LifecycleEventDto:
+			err = b.sink.WriteLifecycleEvent(e)
+		}
+		if err != nil {
+			b.drop(err)
+		}
+	}
+
+	if err := b.sink.Close(); err != nil {
+		b.Lock()
+		b.lastErr = err
+		b.Unlock()
+	}
+}
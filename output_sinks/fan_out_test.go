@@ -0,0 +1,155 @@
+package output_sinks
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/job_config"
+)
+
+//blockingSink records the lines, but only after `release` is closed
+type blockingSink struct {
+	release chan struct{}
+	lines   []string
+	events  []string
+	closed  bool
+}
+
+func (b *blockingSink) WriteLine(event *LineEvent) error {
+	<-b.release
+	b.lines = append(b.lines, event.Text)
+	return nil
+}
+
+func (b *blockingSink) WriteLifecycleEvent(event *exec_logger_dtos.LifecycleEventDto) error {
+	<-b.release
+	b.events = append(b.events, event.Event)
+	return nil
+}
+
+func (b *blockingSink) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestFanOut(t *testing.T) {
+	Convey("Testing FanOut", t, func() {
+		timestamp := time.Date(2016, 5, 7, 10, 0, 0, 0, time.Local)
+		line := func(stream Stream, text string, isError bool) *LineEvent {
+			return &LineEvent{Time: timestamp, Stream: stream, Text: text, IsError: isError}
+		}
+
+		Convey("A slow sink does not block the others and drops when its buffer is full", func() {
+			errs := []string{}
+			fanOut := NewFanOut(func(sinkName string, err error) { errs = append(errs, sinkName) })
+			logBuf := &bytes.Buffer{}
+			slow := &blockingSink{release: make(chan struct{})}
+			fanOut.Add("log", NewFileSink(logBuf, false), 0, nil)
+			fanOut.Add("slow", slow, 2, []Stream{StreamStdout})
+
+			//The first line is taken from the buffer, the next two fill it and the last one is dropped
+			fanOut.WriteLine(line(StreamStdout, "one", false))
+			time.Sleep(50 * time.Millisecond)
+			fanOut.WriteLine(line(StreamStdout, "two", false))
+			fanOut.WriteLine(line(StreamStderr, "skipped by the stream filter", true))
+			fanOut.WriteLine(line(StreamStdout, "three", false))
+			fanOut.WriteLine(line(StreamStdout, "four", false))
+			So(strings.Count(logBuf.String(), "\n"), ShouldEqual, 5)
+			So(logBuf.String(), ShouldContainSubstring, "[2016-05-07 10:00:00] EASY_EXEC_ERROR: skipped by the stream filter")
+
+			close(slow.release)
+			reports := fanOut.Flush(time.Second)
+			So(len(reports), ShouldEqual, 1)
+			So(reports[0].SinkName, ShouldEqual, "slow")
+			So(reports[0].Dropped, ShouldEqual, 1)
+			So(slow.lines, ShouldResemble, []string{"one", "two", "three"})
+			So(slow.closed, ShouldBeTrue)
+
+			//After the flush only the sinks without a buffer get the lines
+			fanOut.WriteLine(line(StreamExecLogger, "done", false))
+			So(logBuf.String(), ShouldContainSubstring, "] done")
+			fanOut.Close()
+			So(errs, ShouldBeEmpty)
+		})
+
+		Convey("Flush gives up after the timeout", func() {
+			fanOut := NewFanOut(nil)
+			slow := &blockingSink{release: make(chan struct{})}
+			fanOut.Add("slow", slow, 10, nil)
+			fanOut.WriteLifecycleEvent(&exec_logger_dtos.LifecycleEventDto{Event: "started"})
+			fanOut.WriteLifecycleEvent(&exec_logger_dtos.LifecycleEventDto{Event: "finished"})
+
+			reports := fanOut.Flush(50 * time.Millisecond)
+			So(len(reports), ShouldEqual, 1)
+			So(reports[0].Dropped, ShouldEqual, 1)
+			So(reports[0].LastErr, ShouldNotBeNil)
+			close(slow.release)
+		})
+
+		Convey("The json-lines sink writes the lines and lifecycle events", func() {
+			tempDir, err := ioutil.TempDir("", "output-sinks")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(tempDir)
+
+			definitions := &Definitions{}
+			err = job_config.ParseDefinitions("sinks.json", []byte(`{"Sinks": [{"Type": "json-lines", "Path": "`+filepath.ToSlash(filepath.Join(tempDir, "events.jsonl"))+`"}]}`), definitions)
+			So(err, ShouldBeNil)
+			So(definitions.Sinks[0].Name, ShouldEqual, "json-lines-1")
+
+			fanOut := NewFanOut(nil)
+			definitions.AddTo(fanOut, func(sinkName string, err error) { t.Errorf("Cannot open sink %s: %s", sinkName, err.Error()) })
+			event := line(StreamStderr, "broken", true)
+			event.Sequence = 7
+			fanOut.WriteLine(event)
+			fanOut.WriteLifecycleEvent(&exec_logger_dtos.LifecycleEventDto{Event: "finished"})
+			So(fanOut.Flush(time.Second), ShouldBeEmpty)
+
+			content, err := ioutil.ReadFile(filepath.Join(tempDir, "events.jsonl"))
+			So(err, ShouldBeNil)
+			jsonLines := strings.Split(strings.TrimSpace(string(content)), "\n")
+			So(len(jsonLines), ShouldEqual, 2)
+
+			dto := &JsonLineDto{}
+			So(json.Unmarshal([]byte(jsonLines[0]), dto), ShouldBeNil)
+			So(dto.Lifecycle, ShouldBeNil)
+			So(dto.Line.Stream, ShouldEqual, StreamStderr)
+			So(dto.Line.Text, ShouldEqual, "broken")
+			So(dto.Line.IsError, ShouldBeTrue)
+			So(dto.Line.Sequence, ShouldEqual, 7)
+
+			dto = &JsonLineDto{}
+			So(json.Unmarshal([]byte(jsonLines[1]), dto), ShouldBeNil)
+			So(dto.Line, ShouldBeNil)
+			So(dto.Lifecycle.Event, ShouldEqual, "finished")
+		})
+
+		Convey("Invalid sinks are reported together", func() {
+			err := job_config.ParseDefinitions("sinks.json", []byte(`{
+				"Sinks": [
+					{"Name": "a", "Type": "file"},
+					{"Name": "a", "Type": "console", "Streams": ["stdin"]},
+					{"Type": "process"},
+					{"Type": "syslog", "Address": "udp://localhost:514", "Facility": "nope"},
+					{"Type": "kafka", "BufferSize": -1}
+				]
+			}`), &Definitions{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "Invalid config file 'sinks.json' with 7 problems:")
+			So(err.Error(), ShouldContainSubstring, "sinks.json:3: sink 1 (a) requires a Path")
+			So(err.Error(), ShouldContainSubstring, "sinks.json:4: sink 2 (a) has a duplicate name")
+			So(err.Error(), ShouldContainSubstring, "unknown stream 'stdin'")
+			So(err.Error(), ShouldContainSubstring, "sink 3 (process-3) requires a Command")
+			So(err.Error(), ShouldContainSubstring, "unknown facility 'nope'")
+			So(err.Error(), ShouldContainSubstring, "unknown type 'kafka'")
+		})
+	})
+}
@@ -0,0 +1,53 @@
+package output_sinks
+
+import (
+	"time"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+)
+
+//Stream is where a line came from
+type Stream string
+
+const (
+	StreamStdout Stream = "stdout"
+	StreamStderr Stream = "stderr"
+	//StreamExecLogger are the messages of exec-logger itself, like the command line and the exit code
+	StreamExecLogger Stream = "exec-logger"
+)
+
+var allStreams = []Stream{StreamStdout, StreamStderr, StreamExecLogger}
+
+//StreamNames returns the names of all the streams
+func StreamNames() (names []string) {
+	for _, stream := range allStreams {
+		names = append(names, string(stream))
+	}
+	return
+}
+
+func isValidStream(stream Stream) bool {
+	for _, s := range allStreams {
+		if s == stream {
+			return true
+		}
+	}
+	return false
+}
+
+//LineEvent is a (redacted) line written to the log. The Sequence numbers the lines of the log, starting at 1
+type LineEvent struct {
+	Time     time.Time
+	Stream   Stream
+	Text     string
+	IsError  bool
+	Sequence int64
+}
+
+//Sink is a destination of the log lines and the lifecycle events (the same as the webhook payloads). The methods are never
+//called concurrently, a sink that is slow (or can block) must be added to the FanOut with a buffer
+type Sink interface {
+	WriteLine(event *LineEvent) error
+	WriteLifecycleEvent(event *exec_logger_dtos.LifecycleEventDto) error
+	Close() error
+}
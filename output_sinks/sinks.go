@@ -0,0 +1,197 @@
+package output_sinks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/golang-devops/exec-logger/exec_logger_constants"
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/syslog_sink"
+)
+
+//ErrorLinePrefix is the prefix of the error lines in the log, the parselog task looks for it
+const ErrorLinePrefix = "EASY_EXEC_ERROR: "
+
+//FormatLogLine formats the line like in the log file, without the line ending
+func FormatLogLine(event *LineEvent) string {
+	text := event.Text
+	if event.IsError {
+		text = ErrorLinePrefix + text
+	}
+	return fmt.Sprintf("[%s] %s", event.Time.Format(exec_logger_constants.LOG_LINE_TIME_FORMAT), text)
+}
+
+func getLineEnding() string {
+	if runtime.GOOS == "windows" {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+type syncer interface {
+	Sync() error
+}
+
+//FileSink writes the lines like in the log file, the lifecycle events are skipped since the log already has lines for them
+type FileSink struct {
+	writer       io.Writer
+	closer       io.Closer
+	lineEnding   string
+	syncEachLine bool
+}
+
+//NewFileSink creates a sink that writes to the writer, the writer is not closed by the sink.
+//With `syncEachLine` the writer is fsynced after every line if it supports that
+func NewFileSink(writer io.Writer, syncEachLine bool) *FileSink {
+	return &FileSink{writer: writer, lineEnding: getLineEnding(), syncEachLine: syncEachLine}
+}
+
+func openFileSink(filePath string) (*FileSink, error) {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot open file '%s', error: %s", filePath, err.Error())
+	}
+	sink := NewFileSink(file, false)
+	sink.closer = file
+	return sink, nil
+}
+
+func (f *FileSink) WriteLine(event *LineEvent) error {
+	if _, err := io.WriteString(f.writer, FormatLogLine(event)+f.lineEnding); err != nil {
+		return fmt.Errorf("Cannot write, error: %s", err.Error())
+	}
+	if f.syncEachLine {
+		if sy, ok := f.writer.(syncer); ok {
+			if err := sy.Sync(); err != nil {
+				return fmt.Errorf("Cannot fsync, error: %s", err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+func (f *FileSink) WriteLifecycleEvent(event *exec_logger_dtos.LifecycleEventDto) error {
+	return nil
+}
+
+func (f *FileSink) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
+}
+
+//consoleSink writes the lines like in the log file to stdout, and the lines of the stderr stream to stderr
+type consoleSink struct {
+	stdout *FileSink
+	stderr *FileSink
+}
+
+func newConsoleSink() *consoleSink {
+	return &consoleSink{stdout: NewFileSink(os.Stdout, false), stderr: NewFileSink(os.Stderr, false)}
+}
+
+func (c *consoleSink) WriteLine(event *LineEvent) error {
+	if event.Stream == StreamStderr {
+		return c.stderr.WriteLine(event)
+	}
+	return c.stdout.WriteLine(event)
+}
+
+func (c *consoleSink) WriteLifecycleEvent(event *exec_logger_dtos.LifecycleEventDto) error {
+	return nil
+}
+
+func (c *consoleSink) Close() error {
+	return nil
+}
+
+//JsonLineDto is a line of a json-lines sink, only one of the fields is set
+type JsonLineDto struct {
+	Line      *LineEvent                          `json:",omitempty"`
+	Lifecycle *exec_logger_dtos.LifecycleEventDto `json:",omitempty"`
+}
+
+//jsonLinesSink writes every line and lifecycle event as a json object on its own line
+type jsonLinesSink struct {
+	writer io.WriteCloser
+}
+
+func openJsonLinesSink(filePath string) (*jsonLinesSink, error) {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot open file '%s', error: %s", filePath, err.Error())
+	}
+	return &jsonLinesSink{writer: file}, nil
+}
+
+func (j *jsonLinesSink) write(dto *JsonLineDto) error {
+	data, err := json.Marshal(dto)
+	if err != nil {
+		return fmt.Errorf("Cannot marshal to json, error: %s", err.Error())
+	}
+	if _, err = j.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("Cannot write, error: %s", err.Error())
+	}
+	return nil
+}
+
+func (j *jsonLinesSink) WriteLine(event *LineEvent) error {
+	return j.write(&JsonLineDto{Line: event})
+}
+
+func (j *jsonLinesSink) WriteLifecycleEvent(event *exec_logger_dtos.LifecycleEventDto) error {
+	return j.write(&JsonLineDto{Lifecycle: event})
+}
+
+func (j *jsonLinesSink) Close() error {
+	return j.writer.Close()
+}
+
+//processSink writes json lines (like the json-lines sink) to the stdin of an external process, its output is discarded
+type processSink struct {
+	*jsonLinesSink
+	cmd *exec.Cmd
+}
+
+func startProcessSink(command []string) (*processSink, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot get stdin pipe of process '%s', error: %s", command[0], err.Error())
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("Cannot start process '%s', error: %s", command[0], err.Error())
+	}
+	return &processSink{jsonLinesSink: &jsonLinesSink{writer: stdin}, cmd: cmd}, nil
+}
+
+//Close closes stdin and waits for the process to exit
+func (p *processSink) Close() error {
+	p.jsonLinesSink.Close()
+	if err := p.cmd.Wait(); err != nil {
+		return fmt.Errorf("The process '%s' failed, error: %s", p.cmd.Path, err.Error())
+	}
+	return nil
+}
+
+//syslogSink sends the lines with the error or info severity, the lifecycle events are skipped
+type syslogSink struct {
+	sink *syslog_sink.Sink
+}
+
+func (s *syslogSink) WriteLine(event *LineEvent) error {
+	return s.sink.Send(event.Time, event.Text, event.IsError)
+}
+
+func (s *syslogSink) WriteLifecycleEvent(event *exec_logger_dtos.LifecycleEventDto) error {
+	return nil
+}
+
+func (s *syslogSink) Close() error {
+	return s.sink.Close()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang-devops/exec-logger/exec_logger_constants"
+)
+
+var (
+	logLineTimestampPattern = regexp.MustCompile(`^\[([0-9]{4}-[0-9]{2}-[0-9]{2} [0-9]{2}:[0-9]{2}:[0-9]{2})\]`)
+
+	parseLogTimeFormats = []string{
+		exec_logger_constants.LOG_LINE_TIME_FORMAT,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+	}
+)
+
+//parseLogTime parses the `-since` and `-until` values. The log lines are written in local time so these are too
+func parseLogTime(s string) (time.Time, error) {
+	for _, format := range parseLogTimeFormats {
+		if t, err := time.ParseInLocation(format, strings.TrimSpace(s), time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Cannot parse time '%s', expected one of the formats: %s", s, strings.Join(parseLogTimeFormats, ", "))
+}
+
+//getLogLineTime returns the time of the `[YYYY-MM-DD HH:MM:SS]` prefix of the line
+func getLogLineTime(line string) (time.Time, bool) {
+	matches := logLineTimestampPattern.FindStringSubmatch(line)
+	if len(matches) < 2 {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(exec_logger_constants.LOG_LINE_TIME_FORMAT, matches[1], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+//logTimeWindow filters lines on their timestamp, lines without a timestamp have the time of the line before them. A zero `since` or `until` means no bound
+type logTimeWindow struct {
+	since time.Time
+	until time.Time
+
+	lastLineTime time.Time
+}
+
+//check returns whether the line is inside the window and whether it is past the window (so no more lines can be inside it)
+func (w *logTimeWindow) check(line string) (inside bool, pastUntil bool) {
+	if lineTime, ok := getLogLineTime(line); ok {
+		w.lastLineTime = lineTime
+	}
+
+	if !w.until.IsZero() && w.lastLineTime.After(w.until) {
+		return false, true
+	}
+	if !w.since.IsZero() && (w.lastLineTime.IsZero() || w.lastLineTime.Before(w.since)) {
+		return false, false
+	}
+	return true, false
+}
+
+type parsedLogLine struct {
+	lineNumber int
+	text       string
+	isError    bool
+}
+
+//contextPrinter prints the selected lines along with `contextLines` lines before and after them, similar to `grep -C`.
+//Only the last `contextLines` lines are kept in memory
+type contextPrinter struct {
+	contextLines   int
+	printLine      func(parsedLogLine)
+	printSeparator func()
+
+	before             []parsedLogLine
+	afterRemaining     int
+	lastPrintedLineNum int
+}
+
+func (c *contextPrinter) print(line parsedLogLine) {
+	if c.contextLines > 0 && c.lastPrintedLineNum > 0 && line.lineNumber > c.lastPrintedLineNum+1 {
+		c.printSeparator()
+	}
+	c.printLine(line)
+	c.lastPrintedLineNum = line.lineNumber
+}
+
+func (c *contextPrinter) add(line parsedLogLine, selected bool) {
+	if selected {
+		for _, b := range c.before {
+			c.print(b)
+		}
+		c.before = c.before[:0]
+		c.print(line)
+		c.afterRemaining = c.contextLines
+		return
+	}
+
+	if c.afterRemaining > 0 {
+		c.print(line)
+		c.afterRemaining--
+		return
+	}
+
+	if c.contextLines > 0 {
+		if len(c.before) == c.contextLines {
+			c.before = append(c.before[:0], c.before[1:]...)
+		}
+		c.before = append(c.before, line)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseLogFilter(t *testing.T) {
+	Convey("Testing contextPrinter", t, func() {
+		printed := []string{}
+		printer := &contextPrinter{
+			contextLines:   1,
+			printLine:      func(line parsedLogLine) { printed = append(printed, line.text) },
+			printSeparator: func() { printed = append(printed, "--") },
+		}
+
+		for i, txt := range []string{"a", "b", "ERR1", "c", "d", "e", "ERR2", "ERR3", "f", "g"} {
+			isError := strings.HasPrefix(txt, "ERR")
+			printer.add(parsedLogLine{lineNumber: i + 1, text: txt, isError: isError}, isError)
+		}
+		So(printed, ShouldResemble, []string{"b", "ERR1", "c", "--", "e", "ERR2", "ERR3", "f"})
+	})
+
+	Convey("Testing logTimeWindow", t, func() {
+		since, err := parseLogTime("2016-05-07 10:00:00")
+		So(err, ShouldBeNil)
+		until, err := parseLogTime("2016-05-07 10:30")
+		So(err, ShouldBeNil)
+
+		_, err = parseLogTime("yesterday")
+		So(err, ShouldNotBeNil)
+
+		window := &logTimeWindow{since: since, until: until}
+		lineAt := func(t time.Time, txt string) string {
+			return fmt.Sprintf("[%s] %s", t.Format("2006-01-02 15:04:05"), txt)
+		}
+
+		inside, past := window.check(lineAt(since.Add(-time.Second), "before"))
+		So(inside, ShouldBeFalse)
+		So(past, ShouldBeFalse)
+
+		inside, _ = window.check(lineAt(since, "first"))
+		So(inside, ShouldBeTrue)
+
+		inside, _ = window.check("continuation line without timestamp")
+		So(inside, ShouldBeTrue)
+
+		inside, past = window.check(lineAt(until.Add(time.Second), "after"))
+		So(inside, ShouldBeFalse)
+		So(past, ShouldBeTrue)
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-zero-boilerplate/loggers"
+	"github.com/golang-devops/exec-logger/job_config"
+	"github.com/golang-devops/exec-logger/log_patterns"
+)
+
+//loadPatternRules combines the rules of the patterns file and then the `-parse_patterns` (as error rules). The returned rules are not compiled yet
+func loadPatternRules(stdioLogger loggers.LoggerStdIO, patternsFilePath, parsePatterns string) (*log_patterns.RuleSet, error) {
+	ruleSet := &log_patterns.RuleSet{}
+
+	if patternsFilePath != "" {
+		fileRuleSet := &log_patterns.RuleSet{}
+		if err := job_config.LoadDefinitions(patternsFilePath, fileRuleSet); err != nil {
+			return nil, err
+		}
+		ruleSet.Rules = append(ruleSet.Rules, fileRuleSet.Rules...)
+		stdioLogger.Out("Loaded %d rules from patterns file %s", len(fileRuleSet.Rules), patternsFilePath)
+	}
+
+	if len(parsePatterns) > 0 {
+		for i, s := range strings.Split(parsePatterns, splitParsePatternString) {
+			if strings.TrimSpace(s) == "" {
+				continue
+			}
+			ruleSet.Add(&log_patterns.Rule{
+				Name:     fmt.Sprintf("parse_patterns-%d", i+1),
+				Pattern:  s,
+				Severity: log_patterns.SeverityError,
+			})
+			stdioLogger.Out("Additional error pattern added: %s", s)
+		}
+	}
+
+	return ruleSet, nil
+}
+
+//buildOutputRuleSet returns the compiled rules to classify the output lines of the exec task, or nil if no rules were given
+func buildOutputRuleSet(stdioLogger loggers.LoggerStdIO, patternsFilePath, parsePatterns string) (*log_patterns.RuleSet, error) {
+	ruleSet, err := loadPatternRules(stdioLogger, patternsFilePath, parsePatterns)
+	if err != nil {
+		return nil, err
+	}
+	if len(ruleSet.Rules) == 0 {
+		return nil, nil
+	}
+
+	if err := ruleSet.Compile(); err != nil {
+		return nil, err
+	}
+	return ruleSet, nil
+}
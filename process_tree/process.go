@@ -15,6 +15,7 @@ type Process struct {
 	Exe        string
 	NumThreads int
 	Cmdline    string
+	CreateTime int64 //Milliseconds since epoch, used along with the Pid to uniquely identify a process
 	Children   []*Process
 }
 
@@ -48,6 +49,11 @@ func (p *Process) loadTreeDetails() error {
 	}
 	p.NumThreads = int(numThreads)
 
+	//Not all platforms support the create time, it is only used to tell apart processes with a reused pid so do not fail on it
+	if createTime, err := psutilProc.CreateTime(); err == nil {
+		p.CreateTime = createTime
+	}
+
 	for _, child := range p.Children {
 		if err := child.loadTreeDetails(); err != nil {
 			return err
@@ -65,6 +71,15 @@ func (p *Process) addWmicChildren(children []*process.Process) {
 	}
 }
 
+//getGoPsUtilChildren returns the children of the process, GoPsUtil returns an error for a process without children
+func getGoPsUtilChildren(p *process2.Process) ([]*process2.Process, error) {
+	children, err := p.Children()
+	if err == process2.ErrorNoChildren {
+		return nil, nil
+	}
+	return children, err
+}
+
 func (p *Process) findAndAddGoPsUtilChildren(children []*process2.Process) error {
 	for _, child := range children {
 		osChildProc, err := os.FindProcess(int(child.Pid))
@@ -73,7 +88,7 @@ func (p *Process) findAndAddGoPsUtilChildren(children []*process2.Process) error
 		}
 		wrappedChild := &Process{Process: osChildProc}
 
-		children, err := child.Children()
+		children, err := getGoPsUtilChildren(child)
 		if err != nil {
 			return fmt.Errorf("Cannot load process (pid %d) children, error: %s", child.Pid, err.Error())
 		}
@@ -94,3 +109,18 @@ func (p *Process) flattenedPids() (pids []int) {
 	}
 	return
 }
+
+func (p *Process) totalNumThreads() int {
+	total := p.NumThreads
+	for _, child := range p.Children {
+		total += child.totalNumThreads()
+	}
+	return total
+}
+
+func (p *Process) walk(visit func(proc *Process, parentPid int), parentPid int) {
+	visit(p, parentPid)
+	for _, child := range p.Children {
+		child.walk(visit, p.Pid)
+	}
+}
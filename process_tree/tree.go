@@ -24,3 +24,16 @@ type ProcessTree struct {
 func (p *ProcessTree) FlattenedPids() []int {
 	return p.MainProcess.flattenedPids()
 }
+
+//TotalNumThreads sums the NumThreads of all the processes in the tree
+func (p *ProcessTree) TotalNumThreads() int {
+	return p.MainProcess.totalNumThreads()
+}
+
+//Walk calls `visit` for every process in the tree (parents before their children). The parentPid of the MainProcess is 0
+func (p *ProcessTree) Walk(visit func(proc *Process, parentPid int)) {
+	if p.MainProcess == nil {
+		return
+	}
+	p.MainProcess.walk(visit, 0)
+}
@@ -38,7 +38,7 @@ func (v *visitorGetTree) VisitLinux() {
 		return
 	}
 
-	children, err := p.Children()
+	children, err := getGoPsUtilChildren(p)
 	if err != nil {
 		v.err = fmt.Errorf("Cannot load process (pid %d) children, error: %s", p.Pid, err.Error())
 		return
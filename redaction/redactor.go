@@ -0,0 +1,151 @@
+package redaction
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	//Marker replaces every redacted secret
+	Marker = "[REDACTED]"
+
+	//MinValueLength is the minimum length of a secret value, shorter values would redact too much of the normal output
+	MinValueLength = 4
+)
+
+//New creates an empty Redactor
+func New() *Redactor {
+	return &Redactor{}
+}
+
+//Redactor replaces secrets in text with the Marker. A nil Redactor does not redact anything
+type Redactor struct {
+	patterns []*regexp.Regexp
+	values   []string
+}
+
+//AddPattern adds a regex of which the matches are redacted. If the regex has capture groups only the groups are redacted, so `token=(\S+)` keeps the `token=`
+func (r *Redactor) AddPattern(pattern string) error {
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("Invalid redact pattern '%s', error: %s", pattern, err.Error())
+	}
+	r.patterns = append(r.patterns, regex)
+	return nil
+}
+
+//AddValue adds a literal secret value. It returns false if the value is ignored because it is shorter than MinValueLength
+func (r *Redactor) AddValue(value string) bool {
+	if len(value) < MinValueLength {
+		return false
+	}
+	for _, v := range r.values {
+		if v == value {
+			return true
+		}
+	}
+	r.values = append(r.values, value)
+	//Longest first so a secret containing another secret is fully redacted
+	sort.Stable(byLengthDesc(r.values))
+	return true
+}
+
+//AddEnvVars adds the values of the variables in `environ` (KEY=VALUE pairs like os.Environ) with the names, which may contain wildcards like `*_TOKEN`.
+//It returns the names (or wildcards) that did not match any variable with a value
+func (r *Redactor) AddEnvVars(names []string, environ []string) (notFound []string) {
+	for _, name := range names {
+		found := false
+		for _, env := range environ {
+			parts := strings.SplitN(env, "=", 2)
+			if len(parts) != 2 || parts[1] == "" {
+				continue
+			}
+			if matched, _ := filepath.Match(name, parts[0]); matched {
+				found = r.AddValue(parts[1]) || found
+			}
+		}
+		if !found {
+			notFound = append(notFound, name)
+		}
+	}
+	return
+}
+
+//LoadSecretsFile adds every line of the file as a secret value. Empty lines and lines starting with # are ignored
+func (r *Redactor) LoadSecretsFile(filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("Cannot open secrets file '%s', error: %s", filePath, err.Error())
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if !r.AddValue(line) {
+			return fmt.Errorf("Secret on line %d of secrets file '%s' is shorter than %d characters", lineNumber, filePath, MinValueLength)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("Cannot read secrets file '%s', error: %s", filePath, err.Error())
+	}
+	return nil
+}
+
+//IsEmpty returns true if there is nothing to redact
+func (r *Redactor) IsEmpty() bool {
+	return r == nil || (len(r.patterns) == 0 && len(r.values) == 0)
+}
+
+//Redact replaces all the secret values and pattern matches in the text with the Marker
+func (r *Redactor) Redact(text string) string {
+	if r.IsEmpty() {
+		return text
+	}
+
+	for _, value := range r.values {
+		text = strings.Replace(text, value, Marker, -1)
+	}
+
+	for _, regex := range r.patterns {
+		if regex.NumSubexp() == 0 {
+			text = regex.ReplaceAllLiteralString(text, Marker)
+			continue
+		}
+		text = redactSubmatches(regex, text)
+	}
+	return text
+}
+
+func redactSubmatches(regex *regexp.Regexp, text string) string {
+	result := ""
+	last := 0
+	for _, indexes := range regex.FindAllStringSubmatchIndex(text, -1) {
+		for group := 1; group*2 < len(indexes); group++ {
+			start, end := indexes[group*2], indexes[group*2+1]
+			if start < last || start == end {
+				//Unmatched, empty or nested groups
+				continue
+			}
+			result += text[last:start] + Marker
+			last = end
+		}
+	}
+	return result + text[last:]
+}
+
+type byLengthDesc []string
+
+func (b byLengthDesc) Len() int           { return len(b) }
+func (b byLengthDesc) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byLengthDesc) Less(i, j int) bool { return len(b[i]) > len(b[j]) }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/go-zero-boilerplate/loggers"
+	"github.com/golang-devops/exec-logger/redaction"
+)
+
+//buildRedactor combines the `-redact-patterns`, `-redact-env` and `-redact-file` into a Redactor, or nil if none were given.
+//The `-redact-env` names are looked up in `environ`, the environment of the command
+func buildRedactor(stdioLogger loggers.LoggerStdIO, redactPatterns, redactEnv, redactFilePath string, environ []string) (*redaction.Redactor, error) {
+	redactor := redaction.New()
+
+	if len(redactPatterns) > 0 {
+		for _, s := range strings.Split(redactPatterns, splitParsePatternString) {
+			if strings.TrimSpace(s) == "" {
+				continue
+			}
+			if err := redactor.AddPattern(s); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	envNames := []string{}
+	for _, name := range strings.Split(redactEnv, ",") {
+		if strings.TrimSpace(name) != "" {
+			envNames = append(envNames, strings.TrimSpace(name))
+		}
+	}
+	for _, name := range redactor.AddEnvVars(envNames, environ) {
+		stdioLogger.Err("No environment variable with a value (of at least %d characters) to redact for '%s'", redaction.MinValueLength, name)
+	}
+
+	if redactFilePath != "" {
+		if err := redactor.LoadSecretsFile(redactFilePath); err != nil {
+			return nil, err
+		}
+	}
+
+	if redactor.IsEmpty() {
+		return nil, nil
+	}
+	return redactor, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+)
+
+//resourceLimits are measured across the whole process tree, a zero value means there is no limit
+type resourceLimits struct {
+	maxMemoryKB  int
+	maxCPUTime   time.Duration
+	maxProcesses int
+}
+
+func (r resourceLimits) isSet() bool {
+	return r.maxMemoryKB > 0 || r.maxCPUTime > 0 || r.maxProcesses > 0
+}
+
+//checkExceeded returns nil if none of the limits were exceeded by the sample or the `cpuSeconds` the command used so far
+func (r resourceLimits) checkExceeded(sample *exec_logger_dtos.ResourceUsageDto, cpuSeconds int) *exec_logger_dtos.ResourceLimitExceededDto {
+	if r.maxMemoryKB > 0 {
+		memoryKB := sample.GetSummedProcessesResourceUsage().MemoryKB
+		if memoryKB > r.maxMemoryKB {
+			return &exec_logger_dtos.ResourceLimitExceededDto{
+				Limit:    "max-memory",
+				MaxValue: formatByteSize(int64(r.maxMemoryKB) * 1024),
+				Observed: formatByteSize(int64(memoryKB) * 1024),
+			}
+		}
+	}
+
+	if r.maxCPUTime > 0 {
+		cpuTime := time.Duration(cpuSeconds) * time.Second
+		if cpuTime > r.maxCPUTime {
+			return &exec_logger_dtos.ResourceLimitExceededDto{
+				Limit:    "max-cpu-time",
+				MaxValue: r.maxCPUTime.String(),
+				Observed: cpuTime.String(),
+			}
+		}
+	}
+
+	if r.maxProcesses > 0 && sample.ProcessTree != nil {
+		processCount := len(sample.ProcessTree.FlattenedPids())
+		if processCount > r.maxProcesses {
+			return &exec_logger_dtos.ResourceLimitExceededDto{
+				Limit:    "max-processes",
+				MaxValue: fmt.Sprintf("%d", r.maxProcesses),
+				Observed: fmt.Sprintf("%d", processCount),
+			}
+		}
+	}
+
+	return nil
+}
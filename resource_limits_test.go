@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/process_tree"
+)
+
+func TestResourceLimits(t *testing.T) {
+	Convey("Testing resourceLimits.checkExceeded", t, func() {
+		newProcess := func(pid int, children ...*process_tree.Process) *process_tree.Process {
+			return &process_tree.Process{Process: &os.Process{Pid: pid}, Children: children}
+		}
+		sample := &exec_logger_dtos.ResourceUsageDto{
+			ProcessTree: &process_tree.ProcessTree{MainProcess: newProcess(100, newProcess(101), newProcess(102, newProcess(103)))},
+			ProcessesResourceUsage: []*exec_logger_dtos.ProcessResourceUsage{
+				&exec_logger_dtos.ProcessResourceUsage{Pid: 100, MemoryKB: 1024 * 1024},
+				&exec_logger_dtos.ProcessResourceUsage{Pid: 101, MemoryKB: 512 * 1024},
+			},
+		}
+
+		testCases := []struct {
+			limits     resourceLimits
+			cpuSeconds int
+			expected   *exec_logger_dtos.ResourceLimitExceededDto
+		}{
+			{resourceLimits{}, 1000, nil},
+			{resourceLimits{maxMemoryKB: 2 * 1024 * 1024}, 0, nil},
+			{resourceLimits{maxMemoryKB: 1024 * 1024}, 0, &exec_logger_dtos.ResourceLimitExceededDto{Limit: "max-memory", MaxValue: "1.0 GB", Observed: "1.5 GB"}},
+			{resourceLimits{maxCPUTime: time.Minute}, 60, nil},
+			{resourceLimits{maxCPUTime: time.Minute}, 61, &exec_logger_dtos.ResourceLimitExceededDto{Limit: "max-cpu-time", MaxValue: "1m0s", Observed: "1m1s"}},
+			{resourceLimits{maxProcesses: 4}, 0, nil},
+			{resourceLimits{maxProcesses: 3}, 0, &exec_logger_dtos.ResourceLimitExceededDto{Limit: "max-processes", MaxValue: "3", Observed: "4"}},
+			//The memory is checked first
+			{resourceLimits{maxMemoryKB: 1024, maxCPUTime: time.Second, maxProcesses: 1}, 10, &exec_logger_dtos.ResourceLimitExceededDto{Limit: "max-memory", MaxValue: "1.0 MB", Observed: "1.5 GB"}},
+		}
+		for _, testCase := range testCases {
+			So(testCase.limits.checkExceeded(sample, testCase.cpuSeconds), ShouldResemble, testCase.expected)
+		}
+
+		Convey("A sample without a process tree does not exceed the max-processes", func() {
+			limits := resourceLimits{maxProcesses: 1}
+			So(limits.checkExceeded(&exec_logger_dtos.ResourceUsageDto{}, 0), ShouldBeNil)
+		})
+	})
+}
@@ -9,8 +9,8 @@ import (
 	"github.com/golang-devops/exec-logger/process_tree"
 )
 
-//FillResourceUsage will fill in all the
-func FillResourceUsage(dto *exec_logger_dtos.ResourceUsageDto, procId int) (warnings []string) {
+//FillResourceUsage will fill in all the resource usage details. The process io metrics are only filled when `withIOMetrics` is true
+func FillResourceUsage(dto *exec_logger_dtos.ResourceUsageDto, procId int, withIOMetrics bool) (warnings []string) {
 	procTree, err := process_tree.LoadProcessTree(procId)
 	if err != nil {
 		warnings = append(warnings, fmt.Sprintf("Cannot get resource usage of pid %d, error: %s", procId, err.Error()))
@@ -54,15 +54,36 @@ func FillResourceUsage(dto *exec_logger_dtos.ResourceUsageDto, procId int) (warn
 			allPids := dto.ProcessTree.FlattenedPids()
 			for _, pid := range allPids {
 				memKB, cpuDuration, err := helper.ProcessUsedCPUAndMemoryKB(pid)
+				if err != nil && !helper.ProcessExists(pid) {
+					//The process exited after the process tree was loaded
+					continue
+				}
 				if err != nil {
 					warnings = append(warnings, fmt.Sprintf("Cannot get CPU+Mem for pid %d, error: %s", pid, err.Error()))
 					continue
 				}
-				processResources = append(processResources, &exec_logger_dtos.ProcessResourceUsage{
+				processResource := &exec_logger_dtos.ProcessResourceUsage{
 					Pid:        pid,
 					MemoryKB:   memKB,
 					CPUSeconds: int(cpuDuration.Seconds()),
-				})
+				}
+
+				if withIOMetrics {
+					ioMetrics, err := helper.ProcessIOMetrics(pid)
+					if err != nil && !helper.ProcessExists(pid) {
+						continue
+					} else if err != nil {
+						warnings = append(warnings, fmt.Sprintf("Cannot get io metrics for pid %d, error: %s", pid, err.Error()))
+					} else {
+						processResource.ReadBytes = ioMetrics.ReadBytes
+						processResource.WriteBytes = ioMetrics.WriteBytes
+						processResource.NumFDs = ioMetrics.NumFDs
+						processResource.NumSockets = ioMetrics.NumSockets
+						processResource.NumThreads = ioMetrics.NumThreads
+					}
+				}
+
+				processResources = append(processResources, processResource)
 			}
 
 			dto.ProcessesResourceUsage = processResources
@@ -13,6 +13,17 @@ type Helper interface {
 	FreeVirtualMemoryKB() (int, error)
 
 	ProcessUsedCPUAndMemoryKB(pid int) (memKB int, cpuDuration time.Duration, returnErr error)
+	ProcessIOMetrics(pid int) (*ProcessIOMetrics, error)
+	ProcessExists(pid int) bool
+}
+
+//ProcessIOMetrics holds the disk I/O and handle counts of a single process
+type ProcessIOMetrics struct {
+	ReadBytes  int64
+	WriteBytes int64
+	NumFDs     int
+	NumSockets int
+	NumThreads int
 }
 
 //NewHelperFromOsType will create a new Helper from the OsType
@@ -28,10 +39,8 @@ func (v *visitorCreateHelper) VisitWindows() {
 	v.helper = &winHelper{}
 }
 func (v *visitorCreateHelper) VisitLinux() {
-	//TODO: Implement linux, should be able to use https://github.com/shirou/gopsutil for many of the methods
-	panic("Linux not yet implemented")
+	v.helper = &psutilHelper{procFsAvailable: true}
 }
 func (v *visitorCreateHelper) VisitDarwin() {
-	//TODO: Implement darwin, should be able to use https://github.com/shirou/gopsutil for many of the methods
-	panic("Darwin not yet implemented")
+	v.helper = &psutilHelper{}
 }
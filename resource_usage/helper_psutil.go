@@ -0,0 +1,147 @@
+package resource_usage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/process"
+)
+
+//psutilHelper uses https://github.com/shirou/gopsutil which supports both linux and darwin
+type psutilHelper struct {
+	procFsAvailable bool
+}
+
+func (p *psutilHelper) CPUPercentage() (int, error) {
+	percentages, err := cpu.Percent(0, false)
+	if err != nil {
+		return 0, fmt.Errorf("Cannot get cpu percentage, error: %s", err.Error())
+	}
+	if len(percentages) == 0 {
+		return 0, fmt.Errorf("No cpu percentage returned")
+	}
+	return int(percentages[0]), nil
+}
+
+func (p *psutilHelper) FreePhysicalMemoryKB() (int, error) {
+	virtualMem, err := mem.VirtualMemory()
+	if err != nil {
+		return 0, fmt.Errorf("Cannot get virtual memory stats, error: %s", err.Error())
+	}
+	return int(virtualMem.Available / 1024), nil
+}
+
+func (p *psutilHelper) FreeVirtualMemoryKB() (int, error) {
+	//Similar to windows' "freevirtualmemory" this is the available physical memory plus free swap
+	freePhysicalKB, err := p.FreePhysicalMemoryKB()
+	if err != nil {
+		return 0, err
+	}
+	swapMem, err := mem.SwapMemory()
+	if err != nil {
+		return 0, fmt.Errorf("Cannot get swap memory stats, error: %s", err.Error())
+	}
+	return freePhysicalKB + int(swapMem.Free/1024), nil
+}
+
+func (p *psutilHelper) ProcessUsedCPUAndMemoryKB(pid int) (memKB int, cpuDuration time.Duration, returnErr error) {
+	proc, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return 0, 0, fmt.Errorf("Cannot load process with pid %d, error: %s", pid, err.Error())
+	}
+
+	memInfo, err := proc.MemoryInfo()
+	if err != nil {
+		return 0, 0, fmt.Errorf("Cannot get memory info of pid %d, error: %s", pid, err.Error())
+	}
+
+	times, err := proc.Times()
+	if err != nil {
+		return 0, 0, fmt.Errorf("Cannot get cpu times of pid %d, error: %s", pid, err.Error())
+	}
+
+	cpuSeconds := times.User + times.System
+	return int(memInfo.RSS / 1024), time.Duration(cpuSeconds * float64(time.Second)), nil
+}
+
+func (p *psutilHelper) ProcessExists(pid int) bool {
+	exists, err := process.PidExists(int32(pid))
+	return err == nil && exists
+}
+
+func (p *psutilHelper) countSockets(proc *process.Process) (int, error) {
+	if !p.procFsAvailable {
+		connections, err := proc.Connections()
+		if err != nil {
+			return 0, err
+		}
+		return len(connections), nil
+	}
+
+	//Much cheaper than loading all connections, the /proc/<pid>/fd symlinks of sockets look like "socket:[12345]"
+	fdDir := filepath.Join("/proc", fmt.Sprintf("%d", proc.Pid), "fd")
+	fdInfos, err := ioutil.ReadDir(fdDir)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, fdInfo := range fdInfos {
+		target, err := os.Readlink(filepath.Join(fdDir, fdInfo.Name()))
+		if err != nil {
+			continue //The fd could have been closed in the meantime
+		}
+		if strings.HasPrefix(target, "socket:") {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (p *psutilHelper) ProcessIOMetrics(pid int) (*ProcessIOMetrics, error) {
+	proc, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return nil, fmt.Errorf("Cannot load process with pid %d, error: %s", pid, err.Error())
+	}
+
+	metrics := &ProcessIOMetrics{}
+
+	ioCounters, err := proc.IOCounters()
+	if err != nil && !isNotImplemented(err) {
+		return nil, fmt.Errorf("Cannot get io counters of pid %d, error: %s", pid, err.Error())
+	} else if err == nil {
+		metrics.ReadBytes = int64(ioCounters.ReadBytes)
+		metrics.WriteBytes = int64(ioCounters.WriteBytes)
+	}
+
+	numFDs, err := proc.NumFDs()
+	if err != nil && !isNotImplemented(err) {
+		return nil, fmt.Errorf("Cannot get num file descriptors of pid %d, error: %s", pid, err.Error())
+	}
+	metrics.NumFDs = int(numFDs)
+
+	numThreads, err := proc.NumThreads()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot get num threads of pid %d, error: %s", pid, err.Error())
+	}
+
+	numSockets, err := p.countSockets(proc)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot count sockets of pid %d, error: %s", pid, err.Error())
+	}
+
+	metrics.NumSockets = numSockets
+	metrics.NumThreads = int(numThreads)
+	return metrics, nil
+}
+
+//isNotImplemented is true for the error gopsutil returns for values it can not get on this platform, like the io counters
+//and number of file descriptors on darwin. The error value is in an internal package of gopsutil, so only its text can be compared
+func isNotImplemented(err error) bool {
+	return err.Error() == "not implemented yet"
+}
@@ -52,6 +52,37 @@ func (w *winHelper) extractSingleIntValue(propToExtract string, wmicArgs []strin
 	return int(valInt), nil
 }
 
+func (w *winHelper) extractInt64Values(propsToExtract []string, wmicArgs []string) (map[string]int64, error) {
+	responseXML, err := wmic_command.Run(wmicArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]int64)
+	for _, res := range responseXML.Results {
+		for _, prop := range res.Properties {
+			for _, propToExtract := range propsToExtract {
+				if !strings.EqualFold(prop.Name, propToExtract) {
+					continue
+				}
+				valInt, err := strconv.ParseInt(prop.Value, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("Could not parse %s '%s' as int, error: %s", propToExtract, prop.Value, err.Error())
+				}
+				values[propToExtract] = valInt
+			}
+		}
+	}
+
+	for _, propToExtract := range propsToExtract {
+		if _, ok := values[propToExtract]; !ok {
+			return nil, fmt.Errorf("Could not find value for '%s', xml: %+v", propToExtract, responseXML)
+		}
+	}
+
+	return values, nil
+}
+
 func (w *winHelper) runExec(cmdLine ...string) ([]byte, error) {
 	out, err := exec.Command(cmdLine[0], cmdLine[1:]...).CombinedOutput()
 	if err != nil {
@@ -158,3 +189,25 @@ func (w *winHelper) ProcessUsedCPUAndMemoryKB(pid int) (memKB int, cpuDuration t
 
 	return memUsageKB, cpuTimeDuration, nil
 }
+
+func (w *winHelper) ProcessIOMetrics(pid int) (*ProcessIOMetrics, error) {
+	props := []string{"ReadTransferCount", "WriteTransferCount", "HandleCount", "ThreadCount"}
+	values, err := w.extractInt64Values(props, []string{"process", "where", fmt.Sprintf("ProcessId=%d", pid), "get", strings.Join(props, ",")})
+	if err != nil {
+		return nil, fmt.Errorf("Cannot get io metrics of pid %d, error: %s", pid, err.Error())
+	}
+
+	//Windows does not have file descriptors, the HandleCount is the closest. Sockets are not available from wmic
+	return &ProcessIOMetrics{
+		ReadBytes:  values["ReadTransferCount"],
+		WriteBytes: values["WriteTransferCount"],
+		NumFDs:     int(values["HandleCount"]),
+		NumThreads: int(values["ThreadCount"]),
+	}, nil
+}
+
+func (w *winHelper) ProcessExists(pid int) bool {
+	//wmic has no result (and so no ProcessId value) for a pid that does not exist
+	_, err := w.extractSingleValue("ProcessId", []string{"process", "where", fmt.Sprintf("ProcessId=%d", pid), "get", "ProcessId"})
+	return err == nil
+}
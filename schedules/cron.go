@@ -0,0 +1,185 @@
+package schedules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//CronExpression is a parsed 5-field cron expression (minute, hour, day of month, month and day of week)
+type CronExpression struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+
+	//Like cron a day matches either the day of month or the day of week when both are restricted (do not start with a *)
+	daysOfMonthRestricted bool
+	daysOfWeekRestricted  bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+var dayOfWeekNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+	//namesOffset is the value of the first name
+	namesOffset int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: monthNames, namesOffset: 1},
+	//7 is also sunday
+	{name: "day of week", min: 0, max: 7, names: dayOfWeekNames},
+}
+
+//ParseCron parses a cron expression like `*/15 8-18 * * mon-fri` or a macro like `@daily`
+func ParseCron(expression string) (*CronExpression, error) {
+	expanded := strings.TrimSpace(expression)
+	if macro, ok := cronMacros[strings.ToLower(expanded)]; ok {
+		expanded = macro
+	}
+
+	parts := strings.Fields(expanded)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("Cron expression '%s' must have %d fields (minute hour day-of-month month day-of-week), got %d", expression, len(cronFields), len(parts))
+	}
+
+	values := make([]map[int]bool, len(cronFields))
+	for i, field := range cronFields {
+		fieldValues, err := field.parse(parts[i])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s '%s' in cron expression '%s', error: %s", field.name, parts[i], expression, err.Error())
+		}
+		values[i] = fieldValues
+	}
+
+	if values[4][7] {
+		values[4][0] = true
+	}
+	return &CronExpression{
+		minutes:               values[0],
+		hours:                 values[1],
+		daysOfMonth:           values[2],
+		months:                values[3],
+		daysOfWeek:            values[4],
+		daysOfMonthRestricted: !strings.HasPrefix(parts[2], "*"),
+		daysOfWeekRestricted:  !strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func (f cronField) parseValue(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return i + f.namesOffset, nil
+		}
+	}
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a number", s)
+	}
+	if value < f.min || value > f.max {
+		return 0, fmt.Errorf("%d is not between %d and %d", value, f.min, f.max)
+	}
+	return value, nil
+}
+
+//parse parses a comma separated list of `*`, values and ranges, each optionally with a /step
+func (f cronField) parse(s string) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, item := range strings.Split(s, ",") {
+		rangePart, step := item, 1
+		if slashIndex := strings.Index(item, "/"); slashIndex >= 0 {
+			rangePart = item[:slashIndex]
+			var err error
+			if step, err = strconv.Atoi(item[slashIndex+1:]); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in '%s'", item)
+			}
+		}
+
+		start, end := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = f.parseValue(bounds[0]); err != nil {
+				return nil, err
+			}
+			if end, err = f.parseValue(bounds[1]); err != nil {
+				return nil, err
+			}
+			if start > end {
+				return nil, fmt.Errorf("the range '%s' is reversed", rangePart)
+			}
+		default:
+			value, err := f.parseValue(rangePart)
+			if err != nil {
+				return nil, err
+			}
+			start = value
+			if step == 1 {
+				end = value
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func (c *CronExpression) matchesDay(t time.Time) bool {
+	dayOfMonth := c.daysOfMonth[t.Day()]
+	dayOfWeek := c.daysOfWeek[int(t.Weekday())]
+	if c.daysOfMonthRestricted && c.daysOfWeekRestricted {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
+
+//Next returns the first time after `after` that matches the expression, in the location of `after`.
+//It returns the zero time if there is none within 5 years (like for the 30th of february)
+func (c *CronExpression) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !c.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
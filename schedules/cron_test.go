@@ -0,0 +1,78 @@
+package schedules
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/job_config"
+)
+
+func TestCron(t *testing.T) {
+	Convey("Testing cron expressions", t, func() {
+		at := func(s string) time.Time {
+			t, err := time.Parse("2006-01-02 15:04", s)
+			So(err, ShouldBeNil)
+			return t
+		}
+		next := func(expression string, after string) string {
+			cron, err := ParseCron(expression)
+			So(err, ShouldBeNil)
+			return cron.Next(at(after)).Format("2006-01-02 15:04 Mon")
+		}
+
+		//2016-05-07 is a saturday
+		So(next("*/15 * * * *", "2016-05-07 10:07"), ShouldEqual, "2016-05-07 10:15 Sat")
+		So(next("*/15 * * * *", "2016-05-07 10:15"), ShouldEqual, "2016-05-07 10:30 Sat")
+		So(next("0 8-18/2 * * mon-fri", "2016-05-07 10:07"), ShouldEqual, "2016-05-09 08:00 Mon")
+		So(next("30 2 1,15 * *", "2016-05-07 10:07"), ShouldEqual, "2016-05-15 02:30 Sun")
+		So(next("@daily", "2016-12-31 23:59"), ShouldEqual, "2017-01-01 00:00 Sun")
+		So(next("0 0 29 feb *", "2016-05-07 10:07"), ShouldEqual, "2020-02-29 00:00 Sat")
+		//Both days restricted means either matches
+		So(next("0 12 13 * 5", "2016-05-07 10:07"), ShouldEqual, "2016-05-13 12:00 Fri")
+		So(next("0 12 * * 7", "2016-05-07 10:07"), ShouldEqual, "2016-05-08 12:00 Sun")
+		//A day field starting with * (like */2) is not restricted, like in cron
+		So(next("0 12 */2 * mon", "2016-05-07 10:07"), ShouldEqual, "2016-05-09 12:00 Mon")
+		So(next("0 12 13 * */2", "2016-05-07 10:07"), ShouldEqual, "2016-08-13 12:00 Sat")
+
+		cron, err := ParseCron("0 0 30 feb *")
+		So(err, ShouldBeNil)
+		So(cron.Next(at("2016-05-07 10:07")).IsZero(), ShouldBeTrue)
+
+		for _, invalid := range []string{"* * * *", "60 * * * *", "* * * foo *", "5-1 * * * *", "*/0 * * * *"} {
+			_, err := ParseCron(invalid)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Testing schedule file validation", t, func() {
+		scheduleFile := &ScheduleFile{}
+		err := job_config.ParseDefinitions("schedules.json", []byte(`{
+			"Schedules": [
+				{"Name": "backup", "Cron": "0 2 * * *", "Shell": "backup.sh", "Jitter": "5m"},
+				{"Name": "sync", "Every": "15m", "Command": ["rsync", "-a", "src", "dst"], "Overlap": "queue"}
+			]
+		}`), scheduleFile)
+		So(err, ShouldBeNil)
+		So(scheduleFile.Schedules[0].Overlap, ShouldEqual, OverlapSkip)
+		So(scheduleFile.Schedules[1].NextRun(time.Date(2016, 5, 7, 10, 0, 0, 0, time.UTC)), ShouldResemble, time.Date(2016, 5, 7, 10, 15, 0, 0, time.UTC))
+		So(scheduleFile.Schedules[0].GetJitter(), ShouldBeLessThan, 5*time.Minute)
+
+		err = job_config.ParseDefinitions("schedules.json", []byte(`{
+			"Schedules": [
+				{"Name": "backup", "Cron": "0 2 * *", "Shell": "backup.sh"},
+				{"Name": "backup", "Every": "soon", "Cron": "@daily", "Overlap": "never"}
+			]
+		}`), &ScheduleFile{})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldStartWith, "Invalid config file 'schedules.json' with 5 problems:")
+		So(err.Error(), ShouldContainSubstring, "schedules.json:3: schedule 1 (backup) has an invalid cron")
+		So(err.Error(), ShouldContainSubstring, "schedules.json:4: schedule 2 (backup) has a duplicate name")
+
+		for _, name := range []string{".", "..", "nightly/backup"} {
+			err = job_config.ParseDefinitions("schedules.json", []byte(`{"Schedules": [{"Name": "`+name+`", "Every": "1h", "Shell": "backup.sh"}]}`), &ScheduleFile{})
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package schedules
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/golang-devops/exec-logger/job_config"
+)
+
+//OverlapPolicy is what to do when a run is due while the previous run of the schedule is still running
+type OverlapPolicy string
+
+const (
+	//OverlapSkip skips the run that is due
+	OverlapSkip OverlapPolicy = "skip"
+	//OverlapQueue starts the run once the previous one finished. At most one run is queued
+	OverlapQueue OverlapPolicy = "queue"
+	//OverlapKillPrevious aborts the previous run and then starts the new one
+	OverlapKillPrevious OverlapPolicy = "kill-previous"
+)
+
+var allOverlapPolicies = []OverlapPolicy{OverlapSkip, OverlapQueue, OverlapKillPrevious}
+
+//OverlapPolicyNames returns the names of all the valid overlap policies
+func OverlapPolicyNames() (names []string) {
+	for _, p := range allOverlapPolicies {
+		names = append(names, string(p))
+	}
+	return
+}
+
+//Schedule is a job that runs at the times of the Cron expression, or Every interval.
+//The job is either the Command (argv) or Shell string, like in a job config file
+type Schedule struct {
+	Name        string
+	Cron        string            `json:",omitempty"`
+	Every       string            `json:",omitempty"`
+	Jitter      string            `json:",omitempty"`
+	Overlap     OverlapPolicy     `json:",omitempty"`
+	Command     []string          `json:",omitempty"`
+	Shell       string            `json:",omitempty"`
+	Env         map[string]string `json:",omitempty"`
+	WorkingDir  string            `json:",omitempty"`
+	TimeoutKill string            `json:",omitempty"`
+
+	cron        *CronExpression
+	every       time.Duration
+	jitter      time.Duration
+	timeoutKill time.Duration
+}
+
+//ScheduleFile is the list of schedules of the schedule task
+type ScheduleFile struct {
+	Schedules []*Schedule
+}
+
+//Compile validates and compiles all the schedules
+func (s *ScheduleFile) Compile() error {
+	problems := job_config.NewDefinitionProblems("schedules")
+	seenNames := make(map[string]bool)
+
+	if len(s.Schedules) == 0 {
+		problems.Add("Schedules", "there are no schedules")
+	}
+
+	for i, schedule := range s.Schedules {
+		scheduleDesc := fmt.Sprintf("schedule %d (%s)", i+1, schedule.Name)
+		schedulePath := fmt.Sprintf("Schedules[%d]", i+1)
+
+		if strings.TrimSpace(schedule.Name) == "" {
+			problems.Add(schedulePath+".Name", "%s has no name", scheduleDesc)
+		} else if strings.ContainsAny(schedule.Name, `/\`) {
+			//The name is used as the run directory of the schedule
+			problems.Add(schedulePath+".Name", "%s has a name with a path separator", scheduleDesc)
+		} else if schedule.Name == "." || schedule.Name == ".." {
+			problems.Add(schedulePath+".Name", "%s can not be named '%s'", scheduleDesc, schedule.Name)
+		} else if seenNames[schedule.Name] {
+			problems.Add(schedulePath+".Name", "%s has a duplicate name", scheduleDesc)
+		}
+		seenNames[schedule.Name] = true
+
+		if (schedule.Cron == "") == (schedule.Every == "") {
+			problems.Add(schedulePath+".Cron", "%s must have either a cron expression or an every interval", scheduleDesc)
+		} else if schedule.Cron != "" {
+			cron, err := ParseCron(schedule.Cron)
+			if err != nil {
+				problems.Add(schedulePath+".Cron", "%s has an invalid cron, error: %s", scheduleDesc, err.Error())
+			}
+			schedule.cron = cron
+		} else {
+			every, err := time.ParseDuration(schedule.Every)
+			if err != nil || every <= 0 {
+				problems.Add(schedulePath+".Every", "%s has an invalid every interval '%s'", scheduleDesc, schedule.Every)
+			}
+			schedule.every = every
+		}
+
+		if schedule.Jitter != "" {
+			jitter, err := time.ParseDuration(schedule.Jitter)
+			if err != nil || jitter < 0 {
+				problems.Add(schedulePath+".Jitter", "%s has an invalid jitter duration '%s'", scheduleDesc, schedule.Jitter)
+			}
+			schedule.jitter = jitter
+		}
+		if schedule.TimeoutKill != "" {
+			timeoutKill, err := time.ParseDuration(schedule.TimeoutKill)
+			if err != nil || timeoutKill < 0 {
+				problems.Add(schedulePath+".TimeoutKill", "%s has an invalid timeout-kill duration '%s'", scheduleDesc, schedule.TimeoutKill)
+			}
+			schedule.timeoutKill = timeoutKill
+		}
+
+		if schedule.Overlap == "" {
+			schedule.Overlap = OverlapSkip
+		}
+		if !isValidOverlapPolicy(schedule.Overlap) {
+			problems.Add(schedulePath+".Overlap", "%s has unknown overlap '%s', expected one of: %s", scheduleDesc, schedule.Overlap, strings.Join(OverlapPolicyNames(), ", "))
+		}
+
+		if len(schedule.Command) > 0 && schedule.Shell != "" {
+			problems.Add(schedulePath+".Shell", "%s can only have one of Command and Shell", scheduleDesc)
+		} else if len(schedule.Command) == 0 && schedule.Shell == "" {
+			problems.Add(schedulePath, "%s must have a Command or Shell", scheduleDesc)
+		}
+	}
+
+	return problems.Err()
+}
+
+//GetTimeoutKill returns the timeout of a run, zero if there is none
+func (s *Schedule) GetTimeoutKill() time.Duration {
+	return s.timeoutKill
+}
+
+//NextRun returns the first scheduled time after `after`, without the jitter. The zero time means it never runs again
+func (s *Schedule) NextRun(after time.Time) time.Time {
+	if s.cron != nil {
+		return s.cron.Next(after)
+	}
+	return after.Add(s.every)
+}
+
+//GetJitter returns a random delay up to the Jitter, to spread runs scheduled at the same time
+func (s *Schedule) GetJitter() time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(s.jitter)))
+}
+
+func isValidOverlapPolicy(overlap OverlapPolicy) bool {
+	for _, p := range allOverlapPolicies {
+		if p == overlap {
+			return true
+		}
+	}
+	return false
+}
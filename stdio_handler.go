@@ -9,49 +9,189 @@ import (
 	"time"
 
 	"github.com/go-zero-boilerplate/loggers"
+	"github.com/golang-devops/exec-logger/log_patterns"
+	"github.com/golang-devops/exec-logger/output_actions"
+	"github.com/golang-devops/exec-logger/output_sinks"
+	"github.com/golang-devops/exec-logger/redaction"
+)
+
+const (
+	logFileSinkName = "log-file"
+	//sinksFlushTimeout is how long exec-logger waits for the buffered sinks before exiting
+	sinksFlushTimeout = 5 * time.Second
+	//defaultOutputDrainTimeout is how long exec-logger keeps reading the output after the command exited, processes started
+	//by the command can inherit its stdout/stderr and keep them open
+	defaultOutputDrainTimeout = 5 * time.Second
 )
 
 type stdioHandler struct {
 	sync.RWMutex
 
 	logger        loggers.LoggerStdIO
-	writer        io.Writer
 	stdoutScanner *bufio.Scanner
 	stderrScanner *bufio.Scanner
+	redactor      *redaction.Redactor
+	//sinks get every (redacted) line, the log file is one of them
+	sinks        *output_sinks.FanOut
+	lineSequence int64
+
+	//outputRules classify the output lines of the command, without them only stderr lines are errors
+	outputRules  *log_patterns.RuleSet
+	matchSummary *log_patterns.MatchSummary
+
+	//outputTriggers are evaluated after every output line is written, `onOutputTrigger` is called for each trigger that fired
+	outputTriggers  *output_actions.TriggerSet
+	onOutputTrigger func(trigger *output_actions.Trigger, line string)
 
 	commandHadStdErr bool
+	stdoutLineCount  int
+	stderrLineCount  int
+	errorMatchCount  int
 }
 
-func (s *stdioHandler) writeFileLine(line string) {
+//newLogFileSinks creates the sinks with the log file, which is written to directly to keep the order and durability of its lines
+func newLogFileSinks(logger loggers.LoggerStdIO, logWriter io.Writer, syncEachLine bool) *output_sinks.FanOut {
+	sinks := output_sinks.NewFanOut(func(sinkName string, err error) {
+		logger.Err("Cannot write to the %s sink, error: %s", sinkName, err.Error())
+	})
+	sinks.Add(logFileSinkName, output_sinks.NewFileSink(logWriter, syncEachLine), 0, nil)
+	return sinks
+}
+
+func (s *stdioHandler) writeLine(stream output_sinks.Stream, line string, isError bool) {
 	s.Lock()
 	defer s.Unlock()
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	_, err := io.WriteString(s.writer, fmt.Sprintf("[%s] %s%s", timestamp, line, NEWLINE))
-	if err != nil {
-		s.logger.Err("Cannot write, error: %s", err.Error())
-	}
+	s.lineSequence++
+	s.sinks.WriteLine(&output_sinks.LineEvent{
+		Time:     time.Now(),
+		Stream:   stream,
+		Text:     s.redactor.Redact(line),
+		IsError:  isError,
+		Sequence: s.lineSequence,
+	})
+}
+
+func (s *stdioHandler) writeFileLine(line string) {
+	s.writeLine(output_sinks.StreamExecLogger, line, false)
+}
+
+//writeWarningLine writes a problem of exec-logger itself that does not fail the command, like a resource usage value that could not be sampled
+func (s *stdioHandler) writeWarningLine(w string) {
+	s.writeFileLine("WARNING: " + w)
 }
 
 func (s *stdioHandler) writeErrorLine(e string) {
+	s.writeStreamErrorLine(output_sinks.StreamExecLogger, e)
+}
+
+func (s *stdioHandler) writeStreamErrorLine(stream output_sinks.Stream, e string) {
 	if strings.TrimSpace(e) == "" {
 		return
 	}
 
+	s.Lock()
 	s.commandHadStdErr = true
-	s.writeFileLine("EASY_EXEC_ERROR: " + e)
+	s.Unlock()
+	s.writeLine(stream, e, true)
+}
+
+//hadStdErr is true if the current command had error lines
+func (s *stdioHandler) hadStdErr() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.commandHadStdErr
+}
+
+//isErrorOutputLine classifies an output line with the output rules. Lines matching an error rule are errors (also on stdout),
+//lines matching an exclude or non-error rule are normal output (also on stderr) and other lines are only errors if they were written to stderr
+func (s *stdioHandler) isErrorOutputLine(line string, isStderr bool) bool {
+	if s.outputRules == nil {
+		return isStderr
+	}
+
+	rule := s.outputRules.Match(line)
+	if rule == nil {
+		return isStderr
+	}
+	s.matchSummary.Add(rule)
+
+	if rule.Exclude || rule.Severity != log_patterns.SeverityError {
+		return false
+	}
+	s.incLineCount(&s.errorMatchCount)
+	return true
+}
+
+//resetCommandErrors is called before every command of a multi-step job, so its errors are judged separately
+func (s *stdioHandler) resetCommandErrors() {
+	s.Lock()
+	defer s.Unlock()
+	s.commandHadStdErr = false
+	s.errorMatchCount = 0
+}
+
+//getErrorMatchCount returns the number of output lines that matched error rules so far
+func (s *stdioHandler) getErrorMatchCount() int {
+	s.RLock()
+	defer s.RUnlock()
+	return s.errorMatchCount
+}
+
+//writeMatchSummary writes the number of matches of each output rule that matched at least once
+func (s *stdioHandler) writeMatchSummary() {
+	if s.matchSummary == nil {
+		return
+	}
+	for _, c := range s.matchSummary.Counts() {
+		if c.Count > 0 {
+			s.writeFileLine(fmt.Sprintf("Output rule '%s' (%s) matched %d lines", c.Rule.Name, c.Rule.Severity, c.Count))
+		}
+	}
+}
+
+func (s *stdioHandler) evaluateOutputTriggers(line string) {
+	if s.outputTriggers == nil || s.onOutputTrigger == nil {
+		return
+	}
+	for _, trigger := range s.outputTriggers.Evaluate(line, time.Now()) {
+		s.onOutputTrigger(trigger, line)
+	}
+}
+
+func (s *stdioHandler) incLineCount(count *int) {
+	s.Lock()
+	defer s.Unlock()
+	*count++
+}
+
+//lineCounts returns the number of lines the command wrote to stdout and stderr so far
+func (s *stdioHandler) lineCounts() (stdout, stderr int) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.stdoutLineCount, s.stderrLineCount
 }
 
 func (s *stdioHandler) startScanningStdout(wg *sync.WaitGroup) {
 	defer wg.Done()
 	for s.stdoutScanner.Scan() {
-		s.writeFileLine(s.stdoutScanner.Text())
+		s.incLineCount(&s.stdoutLineCount)
+		line := s.stdoutScanner.Text()
+		s.writeLine(output_sinks.StreamStdout, line, s.isErrorOutputLine(line, false))
+		s.evaluateOutputTriggers(line)
 	}
 }
 
 func (s *stdioHandler) startScanningStderr(wg *sync.WaitGroup) {
 	defer wg.Done()
 	for s.stderrScanner.Scan() {
-		s.writeErrorLine(s.stderrScanner.Text())
+		s.incLineCount(&s.stderrLineCount)
+		line := s.stderrScanner.Text()
+		if s.isErrorOutputLine(line, true) {
+			s.writeStreamErrorLine(output_sinks.StreamStderr, line)
+		} else {
+			s.writeLine(output_sinks.StreamStderr, line, false)
+		}
+		s.evaluateOutputTriggers(line)
 	}
 }
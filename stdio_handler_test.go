@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/job_config"
+	"github.com/golang-devops/exec-logger/log_patterns"
+)
+
+func TestStdioHandler(t *testing.T) {
+	Convey("Testing stdioHandler output rules", t, func() {
+		ruleSet := &log_patterns.RuleSet{}
+		err := job_config.ParseDefinitions("rules.json", []byte(`{
+			"Rules": [
+				{"Name": "download-progress", "Pattern": "^Downloading", "Exclude": true},
+				{"Name": "compile-error", "Pattern": "error: "},
+				{"Name": "deprecation", "Pattern": "DEPRECATED", "Severity": "warning"}
+			]
+		}`), ruleSet)
+		So(err, ShouldBeNil)
+
+		buf := &bytes.Buffer{}
+		handler := &stdioHandler{
+			sinks:         newLogFileSinks(nil, buf, false),
+			stdoutScanner: bufio.NewScanner(strings.NewReader("building\nmain.c:1: error: missing ;\n")),
+			stderrScanner: bufio.NewScanner(strings.NewReader("Downloading deps\nDEPRECATED flag\nsegfault\n")),
+			outputRules:   ruleSet,
+			matchSummary:  log_patterns.NewMatchSummary(ruleSet),
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		handler.startScanningStdout(&wg)
+		handler.startScanningStderr(&wg)
+		wg.Wait()
+
+		logged := buf.String()
+		So(logged, ShouldContainSubstring, "] building")
+		So(logged, ShouldContainSubstring, "] EASY_EXEC_ERROR: main.c:1: error: missing ;")
+		So(logged, ShouldContainSubstring, "] Downloading deps")
+		So(logged, ShouldContainSubstring, "] DEPRECATED flag")
+		So(logged, ShouldContainSubstring, "] EASY_EXEC_ERROR: segfault")
+
+		So(handler.getErrorMatchCount(), ShouldEqual, 1)
+		So(handler.commandHadStdErr, ShouldBeTrue)
+	})
+
+	Convey("Testing stdioHandler warning lines", t, func() {
+		buf := &bytes.Buffer{}
+		handler := &stdioHandler{sinks: newLogFileSinks(nil, buf, false)}
+		handler.writeWarningLine("Cannot get CPU percentage")
+
+		So(buf.String(), ShouldContainSubstring, "] WARNING: Cannot get CPU percentage")
+		So(buf.String(), ShouldNotContainSubstring, "EASY_EXEC_ERROR")
+		So(handler.hadStdErr(), ShouldBeFalse)
+	})
+}
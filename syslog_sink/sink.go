@@ -0,0 +1,187 @@
+package syslog_sink
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	SeverityError = 3
+	SeverityInfo  = 6
+
+	dialTimeout  = 5 * time.Second
+	writeTimeout = 5 * time.Second
+	//reconnectDelay is how long lines are dropped after the server could not be reached, instead of trying for every line
+	reconnectDelay = 2 * time.Second
+)
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+//LookupFacility returns the number of the facility name, like user or local0
+func LookupFacility(name string) (int, bool) {
+	facility, ok := facilities[strings.ToLower(strings.TrimSpace(name))]
+	return facility, ok
+}
+
+//FacilityNames returns the names of all the facilities
+func FacilityNames() (names []string) {
+	for name := range facilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+//Config is where and how the lines are sent
+type Config struct {
+	//Network is udp, tcp or unix
+	Network  string
+	Address  string
+	AppName  string
+	Facility int
+}
+
+//ParseAddress parses an address like udp://host:514, tcp://host:601 or unix:///dev/log
+func ParseAddress(s string) (network, address string, err error) {
+	parts := strings.SplitN(s, "://", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", fmt.Errorf("Invalid syslog address '%s', expected for example udp://host:514, tcp://host:601 or unix:///dev/log", s)
+	}
+	network, address = strings.ToLower(parts[0]), parts[1]
+	switch network {
+	case "udp", "tcp":
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return "", "", fmt.Errorf("Invalid syslog address '%s', the host must have a port", s)
+		}
+	case "unix":
+	default:
+		return "", "", fmt.Errorf("Unsupported syslog network '%s' in '%s', expected udp, tcp or unix", network, s)
+	}
+	return network, address, nil
+}
+
+//FormatMessage formats a RFC 5424 syslog message, without structured data
+func FormatMessage(facility, severity int, timestamp time.Time, hostName, appName string, procID int, msg string) string {
+	return fmt.Sprintf("<%d>1 %s %s %s %d - - %s",
+		facility*8+severity,
+		timestamp.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		headerField(hostName, 255),
+		headerField(appName, 48),
+		procID,
+		msg)
+}
+
+//headerField replaces the characters not allowed in a header field, which are spaces and non-printable ones
+func headerField(s string, maxLength int) string {
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+	if field == "" {
+		return "-"
+	}
+	if len(field) > maxLength {
+		field = field[:maxLength]
+	}
+	return field
+}
+
+//Sink sends the lines to the syslog server. It is not safe for concurrent use, the buffering is done by the output sinks.
+//After the server could not be reached the lines fail without trying again for a while
+type Sink struct {
+	config   Config
+	hostName string
+	procID   int
+
+	conn           net.Conn
+	reconnectAfter time.Time
+}
+
+//New creates the sink, it connects when the first line is sent
+func New(config Config) *Sink {
+	hostName, _ := os.Hostname()
+	return &Sink{
+		config:   config,
+		hostName: hostName,
+		procID:   os.Getpid(),
+	}
+}
+
+//Send sends the line with error or info severity
+func (s *Sink) Send(timestamp time.Time, line string, isError bool) error {
+	severity := SeverityInfo
+	if isError {
+		severity = SeverityError
+	}
+	msg := FormatMessage(s.config.Facility, severity, timestamp, s.hostName, s.config.AppName, s.procID, line)
+
+	//A stream connection may have been closed by the server since the last line, so retry once with a new connection
+	err := s.send(msg)
+	if err != nil && s.conn == nil && time.Now().After(s.reconnectAfter) {
+		err = s.send(msg)
+	}
+	return err
+}
+
+//Close closes the connection
+func (s *Sink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *Sink) dial() (net.Conn, error) {
+	if s.config.Network != "unix" {
+		return net.DialTimeout(s.config.Network, s.config.Address, dialTimeout)
+	}
+	//The local syslog socket is usually a datagram socket
+	conn, err := net.DialTimeout("unixgram", s.config.Address, dialTimeout)
+	if err != nil {
+		conn, err = net.DialTimeout("unix", s.config.Address, dialTimeout)
+	}
+	return conn, err
+}
+
+func (s *Sink) send(msg string) error {
+	if s.conn == nil {
+		if time.Now().Before(s.reconnectAfter) {
+			return fmt.Errorf("Not connected to syslog %s://%s", s.config.Network, s.config.Address)
+		}
+		conn, err := s.dial()
+		if err != nil {
+			s.reconnectAfter = time.Now().Add(reconnectDelay)
+			return fmt.Errorf("Cannot connect to syslog %s://%s, error: %s", s.config.Network, s.config.Address, err.Error())
+		}
+		s.conn = conn
+	}
+
+	data := msg
+	switch s.conn.LocalAddr().Network() {
+	case "tcp":
+		//Octet counting framing of RFC 6587
+		data = fmt.Sprintf("%d %s", len(msg), msg)
+	case "unix":
+		data = msg + "\n"
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := s.conn.Write([]byte(data)); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("Cannot send to syslog %s://%s, error: %s", s.config.Network, s.config.Address, err.Error())
+	}
+	return nil
+}
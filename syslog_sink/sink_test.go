@@ -0,0 +1,93 @@
+package syslog_sink
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSink(t *testing.T) {
+	Convey("Testing syslog sink", t, func() {
+		Convey("Messages are formatted like RFC 5424", func() {
+			timestamp := time.Date(2016, 5, 7, 10, 0, 0, 123456000, time.UTC)
+			So(FormatMessage(16, SeverityError, timestamp, "build host", "nightly", 42, "EASY_EXEC_ERROR: failed"), ShouldEqual,
+				"<131>1 2016-05-07T10:00:00.123456Z build_host nightly 42 - - EASY_EXEC_ERROR: failed")
+			So(FormatMessage(1, SeverityInfo, timestamp, "", "", 42, "ok"), ShouldEqual, "<14>1 2016-05-07T10:00:00.123456Z - - 42 - - ok")
+		})
+
+		Convey("Addresses are validated", func() {
+			network, address, err := ParseAddress("unix:///dev/log")
+			So(err, ShouldBeNil)
+			So(network+" "+address, ShouldEqual, "unix /dev/log")
+			for _, invalid := range []string{"localhost:514", "udp://localhost", "http://localhost:514"} {
+				_, _, err = ParseAddress(invalid)
+				So(err, ShouldNotBeNil)
+			}
+		})
+
+		Convey("Lines are sent over udp", func() {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			defer conn.Close()
+
+			sink := New(Config{Network: "udp", Address: conn.LocalAddr().String(), AppName: "job", Facility: 1})
+			So(sink.Send(time.Now(), "hello", false), ShouldBeNil)
+			So(sink.Send(time.Now(), "broken", true), ShouldBeNil)
+			So(sink.Close(), ShouldBeNil)
+
+			received := []string{}
+			buf := make([]byte, 1024)
+			for i := 0; i < 2; i++ {
+				conn.SetReadDeadline(time.Now().Add(time.Second))
+				n, _, err := conn.ReadFrom(buf)
+				So(err, ShouldBeNil)
+				received = append(received, string(buf[:n]))
+			}
+			So(received[0], ShouldStartWith, "<14>1 ")
+			So(strings.HasSuffix(received[0], " job "+strconv.Itoa(os.Getpid())+" - - hello"), ShouldBeTrue)
+			So(received[1], ShouldStartWith, "<11>1 ")
+		})
+
+		Convey("Lines are framed with their length over tcp", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			defer listener.Close()
+
+			received := make(chan string, 1)
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				//The sink closes the connection when it is closed
+				content, _ := ioutil.ReadAll(conn)
+				received <- string(content)
+			}()
+
+			sink := New(Config{Network: "tcp", Address: listener.Addr().String(), AppName: "job", Facility: 1})
+			So(sink.Send(time.Now(), "hello", false), ShouldBeNil)
+			So(sink.Close(), ShouldBeNil)
+
+			fields := strings.SplitN(<-received, " ", 2)
+			So(fields[0], ShouldEqual, strconv.Itoa(len(fields[1])))
+			So(strings.HasSuffix(fields[1], " - - hello"), ShouldBeTrue)
+		})
+
+		Convey("Lines fail without reconnecting when the server can not be reached", func() {
+			sink := New(Config{Network: "tcp", Address: "127.0.0.1:1", AppName: "job"})
+			err := sink.Send(time.Now(), "one", false)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "Cannot connect")
+			err = sink.Send(time.Now(), "two", false)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "Not connected")
+		})
+	})
+}
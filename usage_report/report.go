@@ -0,0 +1,204 @@
+package usage_report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/process_tree"
+)
+
+//Report is the analysis of all the samples of a resource-usage file
+type Report struct {
+	StartTime     time.Time
+	EndTime       time.Time
+	NumSamples    int
+	Total         *Stats
+	TotalTimeline []*TimelinePoint
+	Processes     []*ProcessReport
+}
+
+//ProcessReport is the analysis of a single process (unique by Pid and CreateTime)
+type ProcessReport struct {
+	Pid        int
+	Name       string
+	Cmdline    string
+	FirstSeen  time.Time
+	LastSeen   time.Time
+	NumSamples int
+	Stats      *Stats
+	Timeline   []*TimelinePoint
+}
+
+//TimelinePoint is the usage at a single sample time
+type TimelinePoint struct {
+	Time           time.Time
+	ElapsedSeconds float64
+	CPUPercentage  int `json:",omitempty"`
+	ProcessCount   int `json:",omitempty"`
+	MemoryKB       int
+	CPUSeconds     int
+	ReadBytes      int64 `json:",omitempty"`
+	WriteBytes     int64 `json:",omitempty"`
+	NumFDs         int   `json:",omitempty"`
+}
+
+//Stats holds the peaks, averages and percentiles of a timeline
+type Stats struct {
+	PeakMemoryKB    int
+	AverageMemoryKB int
+	P50MemoryKB     int
+	P90MemoryKB     int
+	P95MemoryKB     int
+	P99MemoryKB     int
+	MaxCPUSeconds   int
+	MaxProcessCount int   `json:",omitempty"`
+	MaxReadBytes    int64 `json:",omitempty"`
+	MaxWriteBytes   int64 `json:",omitempty"`
+	MaxNumFDs       int   `json:",omitempty"`
+}
+
+type processKey struct {
+	pid        int
+	createTime int64
+}
+
+//Build analyses the samples, they are expected to be in the order they were recorded
+func Build(samples []*exec_logger_dtos.ResourceUsageDto) *Report {
+	report := &Report{}
+
+	processReports := make(map[processKey]*ProcessReport)
+	processOrder := []processKey{}
+
+	for _, sample := range samples {
+		if len(sample.ProcessesResourceUsage) == 0 {
+			continue
+		}
+
+		if report.NumSamples == 0 {
+			report.StartTime = sample.Time
+		}
+		report.EndTime = sample.Time
+		report.NumSamples++
+		elapsed := sample.Time.Sub(report.StartTime).Seconds()
+
+		summed := sample.GetSummedProcessesResourceUsage()
+		totalPoint := newTimelinePoint(sample.Time, elapsed, summed)
+		totalPoint.CPUPercentage = sample.CPUPercentage
+		totalPoint.ProcessCount = len(sample.ProcessesResourceUsage)
+		report.TotalTimeline = append(report.TotalTimeline, totalPoint)
+
+		processesByPid := make(map[int]*process_tree.Process)
+		if sample.ProcessTree != nil && sample.ProcessTree.MainProcess != nil {
+			addProcessesByPid(processesByPid, sample.ProcessTree.MainProcess)
+		}
+
+		for _, usage := range sample.ProcessesResourceUsage {
+			key := processKey{pid: usage.Pid}
+			proc, hasProc := processesByPid[usage.Pid]
+			if hasProc {
+				key.createTime = proc.CreateTime
+			}
+
+			processReport, ok := processReports[key]
+			if !ok {
+				processReport = &ProcessReport{
+					Pid:       usage.Pid,
+					FirstSeen: sample.Time,
+				}
+				if hasProc {
+					processReport.Name = proc.Name
+					processReport.Cmdline = proc.Cmdline
+				}
+				processReports[key] = processReport
+				processOrder = append(processOrder, key)
+			}
+
+			processReport.LastSeen = sample.Time
+			processReport.NumSamples++
+			processReport.Timeline = append(processReport.Timeline, newTimelinePoint(sample.Time, elapsed, usage))
+		}
+	}
+
+	report.Total = calculateStats(report.TotalTimeline)
+	for _, key := range processOrder {
+		processReport := processReports[key]
+		processReport.Stats = calculateStats(processReport.Timeline)
+		report.Processes = append(report.Processes, processReport)
+	}
+
+	return report
+}
+
+func addProcessesByPid(processesByPid map[int]*process_tree.Process, p *process_tree.Process) {
+	processesByPid[p.Pid] = p
+	for _, child := range p.Children {
+		addProcessesByPid(processesByPid, child)
+	}
+}
+
+func newTimelinePoint(t time.Time, elapsedSeconds float64, usage *exec_logger_dtos.ProcessResourceUsage) *TimelinePoint {
+	return &TimelinePoint{
+		Time:           t,
+		ElapsedSeconds: elapsedSeconds,
+		MemoryKB:       usage.MemoryKB,
+		CPUSeconds:     usage.CPUSeconds,
+		ReadBytes:      usage.ReadBytes,
+		WriteBytes:     usage.WriteBytes,
+		NumFDs:         usage.NumFDs,
+	}
+}
+
+func calculateStats(timeline []*TimelinePoint) *Stats {
+	stats := &Stats{}
+	if len(timeline) == 0 {
+		return stats
+	}
+
+	memoryValues := []int{}
+	totalMemoryKB := int64(0)
+	for _, point := range timeline {
+		memoryValues = append(memoryValues, point.MemoryKB)
+		totalMemoryKB += int64(point.MemoryKB)
+
+		if point.MemoryKB > stats.PeakMemoryKB {
+			stats.PeakMemoryKB = point.MemoryKB
+		}
+		if point.CPUSeconds > stats.MaxCPUSeconds {
+			stats.MaxCPUSeconds = point.CPUSeconds
+		}
+		if point.ProcessCount > stats.MaxProcessCount {
+			stats.MaxProcessCount = point.ProcessCount
+		}
+		if point.ReadBytes > stats.MaxReadBytes {
+			stats.MaxReadBytes = point.ReadBytes
+		}
+		if point.WriteBytes > stats.MaxWriteBytes {
+			stats.MaxWriteBytes = point.WriteBytes
+		}
+		if point.NumFDs > stats.MaxNumFDs {
+			stats.MaxNumFDs = point.NumFDs
+		}
+	}
+
+	sort.Ints(memoryValues)
+	stats.AverageMemoryKB = int(totalMemoryKB / int64(len(timeline)))
+	stats.P50MemoryKB = percentile(memoryValues, 50)
+	stats.P90MemoryKB = percentile(memoryValues, 90)
+	stats.P95MemoryKB = percentile(memoryValues, 95)
+	stats.P99MemoryKB = percentile(memoryValues, 99)
+
+	return stats
+}
+
+//percentile uses the nearest-rank method, the values must already be sorted
+func percentile(sortedValues []int, p int) int {
+	if len(sortedValues) == 0 {
+		return 0
+	}
+	rank := (p*len(sortedValues) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sortedValues[rank-1]
+}
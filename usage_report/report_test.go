@@ -0,0 +1,81 @@
+package usage_report
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/exec_logger_dtos"
+	"github.com/golang-devops/exec-logger/process_tree"
+)
+
+func TestPercentile(t *testing.T) {
+	Convey("Testing percentile", t, func() {
+		values := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+		So(percentile(values, 50), ShouldEqual, 5)
+		So(percentile(values, 90), ShouldEqual, 9)
+		So(percentile(values, 99), ShouldEqual, 10)
+		So(percentile([]int{42}, 50), ShouldEqual, 42)
+		So(percentile([]int{}, 50), ShouldEqual, 0)
+	})
+}
+
+func TestBuild(t *testing.T) {
+	Convey("Testing Build", t, func() {
+		startTime := time.Date(2016, 5, 7, 10, 0, 0, 0, time.UTC)
+		mainProc := &process_tree.Process{Process: &os.Process{Pid: 100}, Name: "make"}
+		childProc := &process_tree.Process{Process: &os.Process{Pid: 101}, Name: "gcc"}
+
+		samples := []*exec_logger_dtos.ResourceUsageDto{}
+		for i := 0; i < 4; i++ {
+			tree := &process_tree.ProcessTree{MainProcess: &process_tree.Process{Process: mainProc.Process, Name: mainProc.Name}}
+			usages := []*exec_logger_dtos.ProcessResourceUsage{
+				&exec_logger_dtos.ProcessResourceUsage{Pid: 100, MemoryKB: 1000, CPUSeconds: i},
+			}
+			if i >= 2 {
+				tree.MainProcess.Children = []*process_tree.Process{childProc}
+				usages = append(usages, &exec_logger_dtos.ProcessResourceUsage{Pid: 101, MemoryKB: 1000 * i, CPUSeconds: 1})
+			}
+			samples = append(samples, &exec_logger_dtos.ResourceUsageDto{
+				Time:                   startTime.Add(time.Duration(i) * time.Second),
+				ProcessTree:            tree,
+				ProcessesResourceUsage: usages,
+			})
+		}
+		//Samples after the process exited are ignored
+		samples = append(samples, &exec_logger_dtos.ResourceUsageDto{Time: startTime.Add(time.Minute)})
+
+		report := Build(samples)
+
+		So(report.NumSamples, ShouldEqual, 4)
+		So(report.EndTime, ShouldResemble, startTime.Add(3*time.Second))
+		So(report.Total, ShouldResemble, &Stats{
+			PeakMemoryKB:    4000,
+			AverageMemoryKB: 2250,
+			P50MemoryKB:     1000,
+			P90MemoryKB:     4000,
+			P95MemoryKB:     4000,
+			P99MemoryKB:     4000,
+			MaxCPUSeconds:   4,
+			MaxProcessCount: 2,
+		})
+
+		So(len(report.Processes), ShouldEqual, 2)
+		So(report.Processes[1].Name, ShouldEqual, "gcc")
+		So(report.Processes[1].NumSamples, ShouldEqual, 2)
+		So(report.Processes[1].FirstSeen, ShouldResemble, startTime.Add(2*time.Second))
+		So(report.Processes[1].Stats.PeakMemoryKB, ShouldEqual, 3000)
+
+		Convey("Csv has a row per total and process timeline point", func() {
+			var buf bytes.Buffer
+			So(WriteCSV(&buf, report), ShouldBeNil)
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			So(len(lines), ShouldEqual, 1+4+4+2)
+			So(lines[1], ShouldStartWith, "2016-05-07T10:00:00Z,0.000,total,,1,0,1000,0")
+		})
+	})
+}
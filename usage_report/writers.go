@@ -0,0 +1,124 @@
+package usage_report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	timeFormat = "2006-01-02 15:04:05"
+	totalPid   = "total"
+)
+
+//WriteText writes a human readable report
+func WriteText(w io.Writer, report *Report) error {
+	if report.NumSamples == 0 {
+		_, err := fmt.Fprintln(w, "No resource usage samples found")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Samples: %d from %s to %s (%s)\n\n", report.NumSamples, report.StartTime.Format(timeFormat), report.EndTime.Format(timeFormat), report.EndTime.Sub(report.StartTime).String())
+
+	fmt.Fprintln(tw, "PID\tNAME\tSAMPLES\tFIRST SEEN\tLAST SEEN\tPEAK KB\tAVG KB\tP50 KB\tP90 KB\tP95 KB\tP99 KB\tCPU SECONDS\t")
+	writeStatsRow(tw, totalPid, "", report.NumSamples, report.StartTime, report.EndTime, report.Total)
+	for _, p := range report.Processes {
+		writeStatsRow(tw, strconv.Itoa(p.Pid), p.Name, p.NumSamples, p.FirstSeen, p.LastSeen, p.Stats)
+	}
+
+	fmt.Fprintln(tw, "\nTotal timeline")
+	fmt.Fprintln(tw, "ELAPSED\tPROCESSES\tCPU %\tMEMORY KB\tCPU SECONDS\t")
+	for _, point := range report.TotalTimeline {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t\n", formatElapsed(point.ElapsedSeconds), point.ProcessCount, point.CPUPercentage, point.MemoryKB, point.CPUSeconds)
+	}
+
+	for _, p := range report.Processes {
+		fmt.Fprintf(tw, "\nTimeline of pid %d (%s)\n", p.Pid, p.Name)
+		fmt.Fprintln(tw, "ELAPSED\tMEMORY KB\tCPU SECONDS\tREAD BYTES\tWRITE BYTES\tFDS\t")
+		for _, point := range p.Timeline {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t\n", formatElapsed(point.ElapsedSeconds), point.MemoryKB, point.CPUSeconds, point.ReadBytes, point.WriteBytes, point.NumFDs)
+		}
+	}
+
+	return tw.Flush()
+}
+
+func writeStatsRow(w io.Writer, pid, name string, numSamples int, firstSeen, lastSeen time.Time, stats *Stats) {
+	fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n",
+		pid, name, numSamples, firstSeen.Format(timeFormat), lastSeen.Format(timeFormat),
+		stats.PeakMemoryKB, stats.AverageMemoryKB, stats.P50MemoryKB, stats.P90MemoryKB, stats.P95MemoryKB, stats.P99MemoryKB, stats.MaxCPUSeconds)
+}
+
+func formatElapsed(seconds float64) string {
+	return (time.Duration(seconds*float64(time.Second)) / time.Millisecond * time.Millisecond).String()
+}
+
+//WriteCSV writes the total and per-process timelines as csv rows. The pid column is "total" for the rows of the total timeline
+func WriteCSV(w io.Writer, report *Report) error {
+	csvWriter := csv.NewWriter(w)
+
+	header := []string{"time", "elapsed_seconds", "pid", "name", "process_count", "cpu_percentage", "memory_kb", "cpu_seconds", "read_bytes", "write_bytes", "num_fds"}
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+
+	writeRows := func(pid, name string, timeline []*TimelinePoint) error {
+		for _, point := range timeline {
+			row := []string{
+				point.Time.Format(time.RFC3339),
+				strconv.FormatFloat(point.ElapsedSeconds, 'f', 3, 64),
+				pid,
+				name,
+				strconv.Itoa(point.ProcessCount),
+				strconv.Itoa(point.CPUPercentage),
+				strconv.Itoa(point.MemoryKB),
+				strconv.Itoa(point.CPUSeconds),
+				strconv.FormatInt(point.ReadBytes, 10),
+				strconv.FormatInt(point.WriteBytes, 10),
+				strconv.Itoa(point.NumFDs),
+			}
+			if err := csvWriter.Write(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := writeRows(totalPid, "", report.TotalTimeline); err != nil {
+		return err
+	}
+	for _, p := range report.Processes {
+		if err := writeRows(strconv.Itoa(p.Pid), p.Name, p.Timeline); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+//WriteJSON writes the full report as indented json
+func WriteJSON(w io.Writer, report *Report) error {
+	jsonBytes, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Cannot marshal report to json, error: %s", err.Error())
+	}
+	_, err = w.Write(append(jsonBytes, '\n'))
+	return err
+}
+
+//Formats are the supported output formats for the report
+var Formats = []struct {
+	Name   string
+	Writer func(io.Writer, *Report) error
+}{
+	{Name: "text", Writer: WriteText},
+	{Name: "csv", Writer: WriteCSV},
+	{Name: "json", Writer: WriteJSON},
+}
@@ -0,0 +1,313 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-devops/exec-logger/job_config"
+)
+
+//Event is a lifecycle event of the command that webhooks can be notified of
+type Event string
+
+const (
+	EventStarted               Event = "started"
+	EventTimedOut              Event = "timed-out"
+	EventAborted               Event = "aborted"
+	EventResourceLimitExceeded Event = "resource-limit-exceeded"
+	EventFinished              Event = "finished"
+)
+
+var allEvents = []Event{EventStarted, EventTimedOut, EventAborted, EventResourceLimitExceeded, EventFinished}
+
+const (
+	//EventHeader has the name of the event
+	EventHeader = "X-Exec-Logger-Event"
+	//SignatureHeader has the hex HMAC-SHA256 of the body with the Secret, like `sha256=9f86d0...`
+	SignatureHeader = "X-Exec-Logger-Signature"
+
+	defaultTimeout    = 10 * time.Second
+	defaultRetries    = 3
+	defaultRetryDelay = 2 * time.Second
+)
+
+//Webhook is an http endpoint that gets a POST with a json payload for each of its Events (all events if empty).
+//A failed delivery is retried `Retries` times, the delay doubles after every attempt
+type Webhook struct {
+	Name       string
+	URL        string
+	Events     []Event           `json:",omitempty"`
+	Headers    map[string]string `json:",omitempty"`
+	Secret     string            `json:",omitempty"`
+	SecretEnv  string            `json:",omitempty"`
+	Timeout    string            `json:",omitempty"`
+	Retries    *int              `json:",omitempty"`
+	RetryDelay string            `json:",omitempty"`
+
+	secret     string
+	timeout    time.Duration
+	retries    int
+	retryDelay time.Duration
+}
+
+//Notifier has the webhooks to send the events to. It is not changed after Compile, so it can be shared by concurrent runs
+//that each have their own Deliveries
+type Notifier struct {
+	Webhooks []*Webhook
+}
+
+//Compile validates and compiles all the webhooks
+func (n *Notifier) Compile() error {
+	problems := job_config.NewDefinitionProblems("webhooks")
+	seenNames := make(map[string]bool)
+
+	for i, webhook := range n.Webhooks {
+		if strings.TrimSpace(webhook.Name) == "" {
+			webhook.Name = fmt.Sprintf("webhook-%d", i+1)
+		}
+		webhookDesc := fmt.Sprintf("webhook %d (%s)", i+1, webhook.Name)
+		webhookPath := fmt.Sprintf("Webhooks[%d]", i+1)
+
+		if seenNames[webhook.Name] {
+			problems.Add(webhookPath+".Name", "%s has a duplicate name", webhookDesc)
+		}
+		seenNames[webhook.Name] = true
+
+		if !strings.HasPrefix(webhook.URL, "http://") && !strings.HasPrefix(webhook.URL, "https://") {
+			problems.Add(webhookPath+".URL", "%s has an invalid URL '%s', expected an http:// or https:// URL", webhookDesc, webhook.URL)
+		}
+		for _, event := range webhook.Events {
+			if !isValidEvent(event) {
+				problems.Add(webhookPath+".Events", "%s has unknown event '%s', expected one of: %s", webhookDesc, event, strings.Join(EventNames(), ", "))
+			}
+		}
+
+		webhook.secret = webhook.Secret
+		if webhook.SecretEnv != "" {
+			if webhook.Secret != "" {
+				problems.Add(webhookPath+".SecretEnv", "%s can only have one of Secret and SecretEnv", webhookDesc)
+			}
+			if webhook.secret = os.Getenv(webhook.SecretEnv); webhook.secret == "" {
+				problems.Add(webhookPath+".SecretEnv", "%s has SecretEnv '%s' but that environment variable is empty", webhookDesc, webhook.SecretEnv)
+			}
+		}
+
+		webhook.timeout = defaultTimeout
+		if webhook.Timeout != "" {
+			timeout, err := time.ParseDuration(webhook.Timeout)
+			if err != nil || timeout <= 0 {
+				problems.Add(webhookPath+".Timeout", "%s has an invalid timeout '%s'", webhookDesc, webhook.Timeout)
+			}
+			webhook.timeout = timeout
+		}
+		webhook.retries = defaultRetries
+		if webhook.Retries != nil {
+			if *webhook.Retries < 0 {
+				problems.Add(webhookPath+".Retries", "%s has a negative number of retries %d", webhookDesc, *webhook.Retries)
+			}
+			webhook.retries = *webhook.Retries
+		}
+		webhook.retryDelay = defaultRetryDelay
+		if webhook.RetryDelay != "" {
+			retryDelay, err := time.ParseDuration(webhook.RetryDelay)
+			if err != nil || retryDelay < 0 {
+				problems.Add(webhookPath+".RetryDelay", "%s has an invalid retry delay '%s'", webhookDesc, webhook.RetryDelay)
+			}
+			webhook.retryDelay = retryDelay
+		}
+	}
+
+	return problems.Err()
+}
+
+func (w *Webhook) wants(event Event) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+//Sign returns the value of the SignatureHeader for the body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type delivery struct {
+	event Event
+	body  []byte
+}
+
+type webhookQueue struct {
+	pending []*delivery
+	sending bool
+}
+
+//Deliveries sends the events of a single run to the webhooks in the background. Every webhook has its own queue, so a slow
+//webhook does not delay the others while each webhook still gets the events of the run in the order they happened
+type Deliveries struct {
+	sync.Mutex
+
+	notifier *Notifier
+	onError  func(webhookName string, err error)
+	queues   map[*Webhook]*webhookQueue
+	sending  sync.WaitGroup
+}
+
+//NewDeliveries returns the deliveries of a new run, it is nil for a nil Notifier.
+//`onError` is called (from another goroutine) for every delivery that still failed after all retries
+func (n *Notifier) NewDeliveries(onError func(webhookName string, err error)) *Deliveries {
+	if n == nil {
+		return nil
+	}
+	return &Deliveries{
+		notifier: n,
+		onError:  onError,
+		queues:   make(map[*Webhook]*webhookQueue),
+	}
+}
+
+//Notify queues the json payload for every webhook that wants the event
+func (d *Deliveries) Notify(event Event, payload interface{}) {
+	if d == nil {
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.onError("", fmt.Errorf("Cannot marshal the payload of event '%s' to json, error: %s", event, err.Error()))
+		return
+	}
+
+	d.Lock()
+	defer d.Unlock()
+	for _, webhook := range d.notifier.Webhooks {
+		if !webhook.wants(event) {
+			continue
+		}
+		queue, ok := d.queues[webhook]
+		if !ok {
+			queue = &webhookQueue{}
+			d.queues[webhook] = queue
+		}
+		queue.pending = append(queue.pending, &delivery{event: event, body: body})
+		if !queue.sending {
+			queue.sending = true
+			d.sending.Add(1)
+			go d.sendQueued(webhook, queue)
+		}
+	}
+}
+
+//sendQueued delivers the queued events of the webhook one after the other, until its queue is empty
+func (d *Deliveries) sendQueued(webhook *Webhook, queue *webhookQueue) {
+	defer d.sending.Done()
+	for {
+		d.Lock()
+		if len(queue.pending) == 0 {
+			queue.sending = false
+			d.Unlock()
+			return
+		}
+		next := queue.pending[0]
+		queue.pending = queue.pending[1:]
+		d.Unlock()
+
+		if err := webhook.deliver(next.event, next.body); err != nil {
+			d.onError(webhook.Name, err)
+		}
+	}
+}
+
+//Wait waits until all the queued events were delivered (or failed after their retries)
+func (d *Deliveries) Wait() {
+	if d == nil {
+		return
+	}
+	d.sending.Wait()
+}
+
+func (w *Webhook) deliver(event Event, body []byte) (err error) {
+	retryDelay := w.retryDelay
+	for attempt := 0; attempt <= w.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(retryDelay)
+			retryDelay *= 2
+		}
+		if err = w.post(event, body); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("Delivery of event '%s' failed after %d attempts, last error: %s", event, w.retries+1, err.Error())
+}
+
+func (w *Webhook) post(event Event, body []byte) error {
+	request, err := http.NewRequest("POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return withoutURL(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range w.Headers {
+		request.Header.Set(name, value)
+	}
+	request.Header.Set(EventHeader, string(event))
+	if w.secret != "" {
+		request.Header.Set(SignatureHeader, Sign(w.secret, body))
+	}
+
+	client := &http.Client{Timeout: w.timeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return withoutURL(err)
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("Unexpected response status %s", response.Status)
+	}
+	return nil
+}
+
+//withoutURL removes the URL from the error of a request, because the URL of a webhook often has a secret token in it
+func withoutURL(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return fmt.Errorf("%s request failed, error: %s", urlErr.Op, urlErr.Err.Error())
+	}
+	return err
+}
+
+//EventNames returns the names of all the valid events
+func EventNames() (names []string) {
+	for _, e := range allEvents {
+		names = append(names, string(e))
+	}
+	return
+}
+
+func isValidEvent(event Event) bool {
+	for _, e := range allEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
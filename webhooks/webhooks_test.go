@@ -0,0 +1,128 @@
+package webhooks
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/golang-devops/exec-logger/job_config"
+)
+
+func TestNotifier(t *testing.T) {
+	Convey("Testing webhook notifications", t, func() {
+		var mutex sync.Mutex
+		received := []string{}
+		failuresLeft := 2
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			body, _ := ioutil.ReadAll(r.Body)
+			if r.URL.Path == "/flaky" && failuresLeft > 0 {
+				failuresLeft--
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			if r.URL.Path == "/down" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if r.Header.Get(SignatureHeader) != "" && r.Header.Get(SignatureHeader) != Sign("s3cret", body) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			received = append(received, r.URL.Path+" "+r.Header.Get(EventHeader)+" "+string(body))
+		}))
+		defer server.Close()
+
+		notifier := &Notifier{}
+		err := job_config.ParseDefinitions("webhooks.json", []byte(`{
+			"Webhooks": [
+				{"Name": "chat", "URL": "`+server.URL+`/chat", "Events": ["finished"], "Secret": "s3cret"},
+				{"Name": "flaky", "URL": "`+server.URL+`/flaky", "Events": ["started"], "RetryDelay": "1ms"},
+				{"Name": "down", "URL": "`+server.URL+`/down", "Events": ["started"], "Retries": 1, "RetryDelay": "1ms"}
+			]
+		}`), notifier)
+		So(err, ShouldBeNil)
+
+		failures := []string{}
+		onError := func(webhookName string, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			failures = append(failures, webhookName+": "+err.Error())
+		}
+		deliveries := notifier.NewDeliveries(onError)
+		deliveries.Notify(EventStarted, map[string]string{"Event": "started"})
+		deliveries.Notify(EventFinished, map[string]string{"Event": "finished"})
+		deliveries.Wait()
+
+		So(received, ShouldContain, `/flaky started {"Event":"started"}`)
+		So(received, ShouldContain, `/chat finished {"Event":"finished"}`)
+		So(len(received), ShouldEqual, 2)
+		So(failures, ShouldResemble, []string{"down: Delivery of event 'started' failed after 2 attempts, last error: Unexpected response status 500 Internal Server Error"})
+
+		Convey("Every run waits only for its own deliveries", func() {
+			slowNotifier := &Notifier{}
+			err := job_config.ParseDefinitions("webhooks.json", []byte(`{
+				"Webhooks": [
+					{"Name": "down", "URL": "`+server.URL+`/down", "Events": ["started"], "Retries": 2, "RetryDelay": "1s"},
+					{"Name": "chat", "URL": "`+server.URL+`/chat", "Events": ["finished"]}
+				]
+			}`), slowNotifier)
+			So(err, ShouldBeNil)
+
+			slowRun := slowNotifier.NewDeliveries(onError)
+			fastRun := slowNotifier.NewDeliveries(onError)
+			slowRun.Notify(EventStarted, map[string]string{"Event": "started"})
+			startTime := time.Now()
+			fastRun.Notify(EventFinished, map[string]string{"Event": "finished"})
+			fastRun.Wait()
+			So(time.Now().Sub(startTime), ShouldBeLessThan, time.Second)
+			slowRun.Wait()
+		})
+
+		Convey("A webhook gets the events of a run in order, even when an earlier delivery is retried", func() {
+			received = nil
+			failuresLeft = 2
+			orderedNotifier := &Notifier{}
+			err := job_config.ParseDefinitions("webhooks.json", []byte(`{
+				"Webhooks": [{"Name": "flaky", "URL": "`+server.URL+`/flaky", "RetryDelay": "50ms"}]
+			}`), orderedNotifier)
+			So(err, ShouldBeNil)
+
+			deliveries := orderedNotifier.NewDeliveries(onError)
+			deliveries.Notify(EventStarted, map[string]string{"Event": "started"})
+			deliveries.Notify(EventTimedOut, map[string]string{"Event": "timed-out"})
+			deliveries.Notify(EventFinished, map[string]string{"Event": "finished"})
+			deliveries.Wait()
+
+			So(received, ShouldResemble, []string{
+				`/flaky started {"Event":"started"}`,
+				`/flaky timed-out {"Event":"timed-out"}`,
+				`/flaky finished {"Event":"finished"}`,
+			})
+		})
+
+		Convey("The URL is not in the error of a failed request, because it often has a secret token", func() {
+			closedServer := httptest.NewServer(http.NotFoundHandler())
+			closedURL := closedServer.URL
+			closedServer.Close()
+
+			webhook := &Webhook{URL: closedURL + "/hooks?token=t0ps3cret", timeout: time.Second}
+			err := webhook.post(EventStarted, []byte("{}"))
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "Post request failed, error: ")
+			So(err.Error(), ShouldNotContainSubstring, "t0ps3cret")
+		})
+
+		err = job_config.ParseDefinitions("webhooks.json", []byte(`{"Webhooks": [{"URL": "ftp://x", "Events": ["exploded"], "Timeout": "soon"}]}`), &Notifier{})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldStartWith, "Invalid config file 'webhooks.json' with 3 problems:")
+		So(err.Error(), ShouldContainSubstring, "webhooks.json:1: webhook 1 (webhook-1) has an invalid URL 'ftp://x'")
+	})
+}